@@ -19,6 +19,7 @@ package schema
 //    - ASDF versions: must be one of:
 //      * "latest": latest stable version
 //      * "lts": latest LTS version (nodejs only)
+//      * "lts/<codename>": latest version of an LTS line, e.g. "lts/jod" (nodejs only)
 //      * Semantic version: "X[.Y[.Z]]" (e.g., "3", "3.12", "3.12.1")
 //    - NPM packages: list of package names
 
@@ -53,13 +54,28 @@ testValidConfigs: {
 		formulae: [string]: [...#Formula]
 		// Casks 
 		casks: [...#Formula]
+		// What to do with outdated packages, globally and per package
+		outdated?: {
+			policy?: "abort" | "warn" | "upgrade"
+			packages?: [string]: "abort" | "warn" | "upgrade" | "hold"
+		}
 	}
 
 	// ASDF version manager configuration
-	asdf!: [string]: #VersionList
+	asdf!: [string]: #VersionList | #AsdfPlugin
+
+	// Runtime manager driven by the asdf section
+	runtime_manager?: "asdf" | "mise"
+
+	// Prerequisites on other managers' packages, keyed by section or section:package
+	requires?: [string]: [...=~"^[a-z]+:.+$"]
 
 	// Global NPM packages
 	npm!: [...string]
+
+	// Directories scanned for project version files
+	// (.tool-versions, .nvmrc, .python-version) whose pinned versions are installed
+	workspace?: roots: [...string]
 }
 
 // Helper to get basename of a package (for sorting)
@@ -71,8 +87,21 @@ testValidConfigs: {
 // Version list with format validation
 #VersionList: [...#Version]
 
+// Plugin with versions and a retention policy for extraneous versions
+#AsdfPlugin: {
+	versions!: #VersionList
+	// keep this many versions older than each resolved version
+	keep_previous?: int & >=0
+	// keep versions pinned by .tool-versions files under these directories
+	keep_if_referenced_by?: [...string]
+	// plugin git repository (default: the asdf plugin index)
+	url?: string
+	// pin the plugin to a branch, tag or commit; pinned plugins are not updated
+	ref?: string
+}
+
 // Valid version formats
-#Version: string & =~"^(latest|lts|\\d+(\\.\\d+){0,2})$"
+#Version: string & =~"^(latest|lts|lts/[a-z]+|\\d+(\\.\\d+){0,2}|system|path:(/|~/).+|ref:\\S+)$"
 
 // Valid formula format (either "name" or "tap/repo/name")
 #Formula: string & =~"^([^/]+|[^/]+/[^/]+/[^/]+)$"
@@ -1,29 +1,51 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/daneroo/dotfiles/go/pkg/asdf"
 	"github.com/daneroo/dotfiles/go/pkg/brewdeps/actual"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/graph"
 	"github.com/daneroo/dotfiles/go/pkg/brewdeps/reconcile"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+	"github.com/daneroo/dotfiles/go/pkg/cache"
 	"github.com/daneroo/dotfiles/go/pkg/completions"
 	"github.com/daneroo/dotfiles/go/pkg/config"
+	"github.com/daneroo/dotfiles/go/pkg/eol"
+	"github.com/daneroo/dotfiles/go/pkg/lock"
 	"github.com/daneroo/dotfiles/go/pkg/npm"
+	"github.com/daneroo/dotfiles/go/pkg/requires"
 )
 
 func main() {
 	f := parseFlags()
 
+	// graph writes the graph alone to stdout, so that it can be redirected: the log goes to stderr
+	graphOut := os.Stdout
+	if f.command == "graph" && f.output == "" {
+		os.Stdout = os.Stderr
+	}
+
 	// Set global execution mode
 	config.Global.Verbose = f.verbose
 
 	// Show global flags and config
 	fmt.Printf("Global Flags:\n")
 	fmt.Printf(" - verbose: %v\n", config.Global.Verbose)
+	fmt.Printf(" - offline: %v\n", f.offline)
 	fmt.Printf("Config: %s\n", f.configFile)
+	fmt.Printf("Command: %s\n", f.command)
 
 	// Load configuration
 	fmt.Printf("\n## Loading Configuration\n\n")
@@ -32,38 +54,191 @@ func main() {
 		log.Fatal(err)
 	}
 
+	switch f.command {
+	case "apply", "plan":
+		apply(cfg, f)
+	case "lock":
+		writeLock(cfg, f)
+	case "refresh-eol":
+		refreshEOL()
+	case "why":
+		why(cfg, f.pkg)
+	case "graph":
+		if err := renderGraph(cfg, f, graphOut); err != nil {
+			fmt.Printf("✗ - %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// apply reconciles every section: brew, asdf, npm and completions,
+// in the order given by the requires section (see requires.Graph.Order).
+// With --locked, versions come from the lock file instead of being resolved.
+// The plan command runs the same steps, showing the commands instead of running them.
+func apply(cfg *config.Config, f flags) {
+	dryRun := f.command == "plan"
+	asdfOpts := asdf.Options{
+		Offline:   f.offline,
+		DryRun:    dryRun,
+		Retention: cfg.AsdfRetention,
+		Workspace: cfg.Workspace,
+		Sources:   cfg.AsdfSources,
+		Manager:   cfg.RuntimeManager,
+		Upgrade:   f.upgrade,
+	}
+	npmOpts := npm.Options{DryRun: dryRun}
+	updateOpts := actual.UpdateOptions{
+		Skip:     f.noUpdate || f.offline,
+		Interval: f.updateInterval,
+	}
+	var lockFile *lock.Lock
+	if f.locked {
+		var err error
+		fmt.Printf("\n## Lock File\n\n")
+		lockFile, err = lock.Load(lock.PathFor(f.configFile))
+		if err != nil {
+			log.Fatal(err)
+		}
+		lockFile.ReportConfigDrift(cfg)
+		asdfOpts.Locked = lockFile.AsdfVersions(cfg)
+		npmOpts.Locked = lockFile.Npm
+	}
+
+	reqs, err := requires.New(cfg.Requires)
+	if err != nil {
+		log.Fatal(err)
+	}
+	order, err := reqs.Order()
+	if err != nil {
+		fmt.Printf("✗ - %v\n", err)
+		os.Exit(1)
+	}
+	if config.Global.Verbose {
+		fmt.Printf("\nSection order: %s\n", strings.Join(order, " -> "))
+	}
+
+	sections := map[string]func(){
+		"brew":        func() { applyBrew(cfg, lockFile, dryRun, f.yes, updateOpts) },
+		"asdf":        func() { applyAsdf(cfg, asdfOpts) },
+		"npm":         func() { applyNpm(cfg, npmOpts) },
+		"completions": func() { applyCompletions(dryRun) },
+	}
+	for _, section := range order {
+		if err := reqs.Check(section, installed(cfg), dryRun); err != nil {
+			fmt.Printf("✗ - %v\n", err)
+			os.Exit(1)
+		}
+		sections[section]()
+	}
+}
+
+// applyBrew reconciles the brew section, once outdated packages pass the
+// outdated policy (see reconcile.GateOutdated)
+func applyBrew(cfg *config.Config, lockFile *lock.Lock, dryRun, yes bool, updateOpts actual.UpdateOptions) {
 	fmt.Printf("\n## Brew Section\n\n")
-	// Check for updates first
-	hasUpdates, err := actual.CheckOutdated()
+	// Update the index first, so that the state knows which packages are outdated
+	store, err := cache.NewDefaultStore()
+	if err != nil {
+		handleError(err)
+	}
+	indexUpdated, err := actual.Update(store, updateOpts)
 	if err != nil {
 		handleError(err)
 	}
-	if hasUpdates {
+	state, err := actual.GetActual()
+	if err != nil {
+		handleError(err)
+	}
+
+	// Check for updates first
+	gate := reconcile.GateOutdated(actual.Outdated(state), cfg.Homebrew, cfg.BrewOutdated, state)
+	gate.IndexUpdated = indexUpdated
+	if gate.Report() {
 		fmt.Printf("\nNote: Must resolve outdated packages before proceeding with brewDeps reconciliation\n")
 		fmt.Printf("      because outdated packages can break dependency resolution\n")
+		fmt.Printf("      (or set their policy to warn, upgrade or hold: homebrew.outdated in the config)\n")
 		os.Exit(1) // Exit before reconciliation if updates needed
 	}
+	if err := actual.Pin(gate.Unpinned, dryRun); err != nil {
+		handleError(err)
+	}
+	if len(gate.Upgrade) > 0 {
+		upgrades := gate.Upgrade
+		if !dryRun {
+			upgrades = confirmMajors(upgrades, yes)
+		}
+		if err := actual.Upgrade(upgrades, dryRun); err != nil {
+			handleError(err)
+		}
+		if !dryRun {
+			// upgrades may change dependencies
+			if state, err = actual.GetActual(); err != nil {
+				handleError(err)
+			}
+		}
+	}
 
-	err = reconcile.Reconcile(cfg.Homebrew)
-	if err != nil {
+	if err := reconcile.Reconcile(cfg.Homebrew, state); err != nil {
+		handleError(err)
+	}
+	if lockFile != nil {
+		lockFile.ReportBrewDrift(cfg.Homebrew, state.Versions(cfg.Homebrew))
+	}
+	if err := reportBrewEOL(cfg.Homebrew, state.Versions(cfg.Homebrew)); err != nil {
 		handleError(err)
 	}
+}
+
+// confirmMajors returns the packages to upgrade: major upgrades are left out
+// unless confirmed, with --yes or at the prompt (a closed stdin declines)
+func confirmMajors(pkgs []actual.OutdatedPackage, yes bool) []actual.OutdatedPackage {
+	majors := actual.Majors(pkgs)
+	if len(majors) == 0 || yes {
+		return pkgs
+	}
+	var names []string
+	for _, p := range majors {
+		names = append(names, p.Name)
+	}
+	fmt.Printf("△ - Major upgrades: %s. Upgrade them? [y/N] ", strings.Join(names, ", "))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a == "y" || a == "yes" {
+		return pkgs
+	}
+	fmt.Printf("\n✗ - Not upgrading %s: confirm, or run with --yes\n", strings.Join(names, ", "))
+	var minors []actual.OutdatedPackage
+	for _, p := range pkgs {
+		if p.Bump != actual.BumpMajor {
+			minors = append(minors, p)
+		}
+	}
+	return minors
+}
 
-	fmt.Printf("\n## ASDF Section\n\n")
+func applyAsdf(cfg *config.Config, asdfOpts asdf.Options) {
+	fmt.Printf("\n## ASDF Section (%s)\n\n", cfg.RuntimeManager)
 	// Handle asdf plugins and versions
-	if err := asdf.Reconcile(cfg.Asdf); err != nil {
+	if err := asdf.Reconcile(cfg.Asdf, asdfOpts); err != nil {
 		fmt.Printf("✗ - %v\n", err)
 		os.Exit(1)
 	}
+}
 
+func applyNpm(cfg *config.Config, npmOpts npm.Options) {
 	fmt.Printf("\n## NPM Globals Section\n\n")
 	// Handle npm global packages
-	if err := npm.Reconcile(cfg.Npm); err != nil {
+	if err := npm.Reconcile(cfg.Npm, npmOpts); err != nil {
 		fmt.Printf("✗ - %v\n", err)
 		os.Exit(1)
 	}
+}
 
+func applyCompletions(dryRun bool) {
 	fmt.Printf("\n## CLI Completions Section\n\n")
+	if dryRun {
+		fmt.Printf("△ - plan: skipping completion caches\n")
+		return
+	}
 	// Cache bash completions to files (avoids slow `source <(cmd completion bash)` at shell startup)
 	completionSpecs := []completions.CompletionSpec{
 		{Name: "npm", Command: "npm", Args: []string{"completion"}, OutputFile: "./core/.config/bash_includes/npm_completion.sh"},
@@ -82,9 +257,120 @@ func main() {
 	}
 }
 
+// installed checks the prerequisites declared in the requires section,
+// asking each manager for its installed packages
+func installed(cfg *config.Config) requires.Installed {
+	return func(ref requires.Ref) (bool, error) {
+		switch ref.Manager {
+		case "brew":
+			versions, err := actual.GetVersions([]types.Package{{Name: ref.Name}, {Name: ref.Name, IsCask: true}})
+			return len(versions) > 0, err
+		case "asdf":
+			versions, err := asdf.Installed(ref.Name, asdf.Options{Manager: cfg.RuntimeManager})
+			return len(versions) > 0, err
+		case "npm":
+			versions, err := npm.InstalledVersions()
+			_, ok := versions[ref.Name]
+			return ok, err
+		}
+		return false, fmt.Errorf("unknown manager %q", ref.Manager)
+	}
+}
+
+// writeLock resolves the configuration to concrete versions and writes config.lock
+func writeLock(cfg *config.Config, f flags) {
+	l, err := lock.Generate(cfg, asdf.Options{Offline: f.offline, Manager: cfg.RuntimeManager})
+	if err != nil {
+		fmt.Printf("✗ - %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n")
+	if err := l.Write(lock.PathFor(f.configFile)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// reportBrewEOL warns about installed runtime formulae (node, python@3.12, go, ...)
+// that are past, or nearing, their end-of-life
+func reportBrewEOL(desired []config.BrewPackage, installed map[types.Package]string) error {
+	store, err := cache.NewDefaultStore()
+	if err != nil {
+		return err
+	}
+	dataset, err := eol.Load(store)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range desired {
+		product := eol.Product(path.Base(pkg.Name))
+		v, ok := installed[pkg]
+		if pkg.IsCask || product == "" || !ok {
+			continue
+		}
+		if warning := dataset.Warning(product, v, time.Now()); warning != "" {
+			fmt.Printf("△ - brew %s: %s (end-of-life data: %s)\n", pkg.Name, warning, dataset.Source)
+		}
+	}
+	return nil
+}
+
+// why explains why a brew package is installed
+func why(cfg *config.Config, name string) {
+	fmt.Printf("\n## Why %s\n\n", name)
+	state, err := actual.GetActual()
+	if err != nil {
+		handleError(err)
+	}
+	fmt.Printf("\n")
+	if err := reconcile.Why(name, cfg.Homebrew, cfg.BrewSections, state); err != nil {
+		fmt.Printf("✗ - %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// renderGraph writes the brew dependency graph, to the output file or else to w
+func renderGraph(cfg *config.Config, f flags, w io.Writer) error {
+	fmt.Printf("\n## Brew Dependency Graph\n\n")
+	state, err := actual.GetActual()
+	if err != nil {
+		handleError(err)
+	}
+	if f.output == "" {
+		return reconcile.RenderGraph(w, f.format, f.pkg, cfg.Homebrew, state)
+	}
+
+	out, err := os.Create(f.output)
+	if err != nil {
+		return err
+	}
+	if err := reconcile.RenderGraph(out, f.format, f.pkg, cfg.Homebrew, state); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", f.output, err)
+	}
+	fmt.Printf("✓ - Graph written (%s)\n", f.output)
+	return nil
+}
+
+// refreshEOL fetches the end-of-life dataset into the cache
+func refreshEOL() {
+	fmt.Printf("\n## End-of-Life Data\n\n")
+	store, err := cache.NewDefaultStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := eol.Refresh(store, eol.DefaultBaseURL, &http.Client{Timeout: 30 * time.Second}); err != nil {
+		fmt.Printf("✗ - %v\n", err)
+		os.Exit(1)
+	}
+}
+
 // handleError handles both validation errors and unexpected errors
 func handleError(err error) {
-	if validErr, ok := err.(*actual.ValidationError); ok {
+	var validErr *actual.ValidationError
+	if errors.As(err, &validErr) {
 		fmt.Printf("✗ - Dependency map inconsistency\n")
 		fmt.Printf(" ...%v\n", validErr)
 		// exit instead of log.Fatal to avoid duplicate output
@@ -96,17 +382,90 @@ func handleError(err error) {
 type flags struct {
 	verbose    bool
 	configFile string
-	// TODO: Add execution mode flags
-	// dryRun bool - Show commands vs Execute them
-	// force bool - Skip confirmation
+	offline    bool
+	// command is the subcommand: apply (default), plan, lock, refresh-eol, why or graph.
+	// plan shows commands instead of executing them (dry run)
+	command string
+	// locked (apply, plan) uses the versions recorded in the lock file
+	locked bool
+	// upgrade (apply, plan) removes asdf versions superseded by a newer patch
+	upgrade bool
+	// noUpdate (apply, plan) skips brew update, updateInterval throttles it
+	noUpdate       bool
+	updateInterval time.Duration
+	// pkg is the brew package to explain (why), or to render the graph around (graph --around)
+	pkg string
+	// format (graph) is dot, mermaid or json; output is the file it is written to
+	// (default: stdout, with the log on stderr)
+	format string
+	output string
+	// yes (apply) confirms major brew upgrades without asking
+	yes bool
 }
 
+// parseFlags parses global flags, then the subcommand and its own flags:
+//
+//	checkdeps [global flags] [apply|plan [--locked] [--upgrade] [--no-update] [--update-interval <duration>] [--yes] |
+//	                            lock | refresh-eol | why <package> |
+//	                            graph [--format dot|mermaid|json] [--around <package>] [--output <file>]]
 func parseFlags() flags {
 	f := flags{}
 	flag.BoolVar(&f.verbose, "verbose", false, "turn on verbose logging")
 	flag.BoolVar(&f.verbose, "v", false, "turn on verbose logging (shorthand)")
 	flag.StringVar(&f.configFile, "config", "config.yaml", "path to config file")
 	flag.StringVar(&f.configFile, "c", "config.yaml", "path to config file (shorthand)")
+	flag.BoolVar(&f.offline, "offline", false, "resolve asdf versions from cached catalogs and installed versions only, and skip brew update")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: checkdeps [flags] [command]\n\nCommands:\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  apply [--locked] [--upgrade] [--no-update] [--update-interval <duration>] [--yes]\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "                                reconcile brew, asdf, npm and completions (default)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  plan [same flags as apply]    show what apply would do, without changing anything\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  lock                          write the resolved versions to the lock file (config.lock)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  refresh-eol                   fetch the runtime end-of-life dataset from endoflife.date\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  why <package>                 show which configured packages require a brew package\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  graph [--format dot|mermaid|json] [--around <package>] [--output <file>]\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "                                render the brew dependency graph\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+
+	f.command = "apply"
+	args := flag.Args()
+	if len(args) > 0 {
+		f.command, args = args[0], args[1:]
+	}
+
+	cmd := flag.NewFlagSet(f.command, flag.ExitOnError)
+	switch f.command {
+	case "apply", "plan":
+		cmd.BoolVar(&f.locked, "locked", false, "install exactly the versions recorded in the lock file")
+		cmd.BoolVar(&f.upgrade, "upgrade", false, "uninstall asdf versions superseded by a newer patch (e.g. 3.12.7 -> 3.12.8)")
+		cmd.BoolVar(&f.noUpdate, "no-update", false, "do not run brew update: outdated packages are as of the last update")
+		cmd.DurationVar(&f.updateInterval, "update-interval", 6*time.Hour, "only run brew update when the last update is older (0: always)")
+		cmd.BoolVar(&f.yes, "yes", false, "upgrade across major versions without asking (outdated policy upgrade)")
+	case "graph":
+		cmd.StringVar(&f.format, "format", "dot", "graph format: "+strings.Join(graph.Formats, ", "))
+		cmd.StringVar(&f.pkg, "around", "", "only render the packages a package depends on, and those depending on it")
+		cmd.StringVar(&f.output, "output", "", "write the graph to a file instead of stdout")
+	case "lock", "refresh-eol", "why":
+	default:
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n\n", f.command)
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd.Parse(args)
+	if f.command == "graph" && !slices.Contains(graph.Formats, f.format) {
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown graph format %q\n\n", f.format)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if f.command == "why" {
+		if cmd.NArg() != 1 {
+			fmt.Fprintf(flag.CommandLine.Output(), "why takes one package\n\n")
+			flag.Usage()
+			os.Exit(2)
+		}
+		f.pkg = cmd.Arg(0)
+	}
 	return f
 }
@@ -0,0 +1,163 @@
+package asdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+	"github.com/daneroo/dotfiles/go/pkg/eol"
+	"github.com/daneroo/dotfiles/go/pkg/nodejs"
+)
+
+// catalogTTL is how long a cached `asdf list all` catalog is used before
+// asking asdf (and therefore GitHub) again.
+const catalogTTL = 24 * time.Hour
+
+// catalog provides the list of available versions per plugin (`asdf list all <plugin>`),
+// cached in ~/.cache/checkdeps/<manager>/<plugin>.txt to avoid GitHub API rate limiting.
+//
+// Online, a cached catalog younger than catalogTTL is used as is, otherwise it is refreshed.
+// If refreshing fails, the stale catalog is used with a warning.
+// Offline, only the cached catalog is used, whatever its age.
+type catalog struct {
+	offline bool
+	store   cache.Store
+	manager RuntimeManager
+	// failed holds the refresh errors of plugins whose catalog could not be
+	// prefetched (see refresh), so listAll does not ask again
+	failed map[string]error
+}
+
+func catalogKey(manager, plugin string) string {
+	return manager + "/" + plugin + ".txt"
+}
+
+func (c catalog) key(plugin string) string {
+	return catalogKey(c.manager.Name(), plugin)
+}
+
+// listAll returns the available versions for a plugin.
+func (c catalog) listAll(plugin string) ([]string, error) {
+	cached, err := c.store.Read(c.key(plugin))
+	hasCache := err == nil
+	if err != nil && !errors.Is(err, cache.ErrNotCached) {
+		return nil, err
+	}
+
+	if c.offline {
+		if !hasCache {
+			return nil, fmt.Errorf("offline: %s catalog %w in %s", plugin, cache.ErrNotCached, c.store.Dir)
+		}
+		reportCatalogAge(plugin, cached)
+		return strings.Fields(string(cached.Data)), nil
+	}
+
+	if hasCache && cached.Age() < catalogTTL {
+		return strings.Fields(string(cached.Data)), nil
+	}
+
+	var versions []string
+	err = c.failed[plugin]
+	if err == nil {
+		versions, err = c.manager.ListAvailable(plugin)
+	}
+	if err != nil {
+		if hasCache {
+			fmt.Printf("△ - failed to refresh %s catalog: %v\n", plugin, err)
+			reportCatalogAge(plugin, cached)
+			return strings.Fields(string(cached.Data)), nil
+		}
+		return nil, err
+	}
+
+	entry := cache.Entry{Data: []byte(strings.Join(versions, "\n") + "\n"), FetchedAt: time.Now()}
+	if err := c.store.Write(c.key(plugin), entry); err != nil {
+		// a broken cache should not prevent resolution
+		fmt.Printf("△ - %v\n", err)
+	}
+	return versions, nil
+}
+
+// refresh fetches a missing or stale catalog into the cache, without reporting anything:
+// listAll then reads and reports it. Offline, there is nothing to refresh.
+func (c catalog) refresh(plugin string) error {
+	if c.offline {
+		return nil
+	}
+	if cached, err := c.store.Read(c.key(plugin)); err == nil && cached.Age() < catalogTTL {
+		return nil
+	}
+	versions, err := c.manager.ListAvailable(plugin)
+	if err != nil {
+		return err
+	}
+	entry := cache.Entry{Data: []byte(strings.Join(versions, "\n") + "\n"), FetchedAt: time.Now()}
+	// a write error is not a refresh error: listAll fetches again, and reports it
+	_ = c.store.Write(c.key(plugin), entry)
+	return nil
+}
+
+// reportCatalogAge shows which catalog is used, warning when it is stale.
+func reportCatalogAge(plugin string, entry cache.Entry) {
+	age := cache.FormatAge(entry.Age())
+	if entry.Age() > catalogTTL {
+		fmt.Printf("△ - %s catalog is stale (age %s), new versions may be missing\n", plugin, age)
+		return
+	}
+	fmt.Printf("✓ - %s catalog from cache (age %s)\n", plugin, age)
+}
+
+// resolver turns version specs into concrete versions, see resolveVersion.
+// Offline, specs are resolved from the cached catalog and installed versions only.
+type resolver struct {
+	offline bool
+	store   cache.Store
+	manager RuntimeManager
+	catalog catalog
+	// scanned memoizes .tool-versions scans by root directory
+	scanned map[string]references
+	// eol is the end-of-life dataset, loaded on first use (see reportEOL)
+	eol *eol.Dataset
+	// node fetches the Node.js release index, read at most once
+	node *nodejs.Client
+	// installed holds the installed versions of plugins, collected by collectState
+	installed map[string][]string
+}
+
+func newResolver(opts Options) (*resolver, error) {
+	manager, err := NewManager(opts.Manager)
+	if err != nil {
+		return nil, err
+	}
+	store, err := cache.NewDefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	node := nodejs.NewClient(store)
+	node.Offline = opts.Offline
+	return &resolver{
+		offline:   opts.Offline,
+		store:     store,
+		manager:   manager,
+		catalog:   catalog{offline: opts.Offline, store: store, manager: manager, failed: make(map[string]error)},
+		scanned:   make(map[string]references),
+		node:      node,
+		installed: make(map[string][]string),
+	}, nil
+}
+
+// available returns the versions that specs can resolve to:
+// the catalog, plus the installed versions (which may predate the catalog).
+// Offline, a missing catalog is not an error: installed versions are used alone.
+func (r *resolver) available(plugin string, installed []string) ([]string, error) {
+	versions, err := r.catalog.listAll(plugin)
+	if err != nil {
+		if !r.offline || !errors.Is(err, cache.ErrNotCached) {
+			return nil, err
+		}
+		fmt.Printf("△ - %v, resolving from installed versions only\n", err)
+	}
+	return append(versions, installed...), nil
+}
@@ -0,0 +1,55 @@
+package asdf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+)
+
+func TestOfflineResolution(t *testing.T) {
+	store := cache.Store{Dir: t.TempDir()}
+	entry := cache.Entry{
+		Data:      []byte("3.11.10\n3.12.7\n3.12.8\n3.13.0rc1\n3.13.0\n"),
+		FetchedAt: time.Now().Add(-72 * time.Hour), // stale, still used offline
+	}
+	if err := store.Write(catalogKey("asdf", "python"), entry); err != nil {
+		t.Fatal(err)
+	}
+	m := asdfManager{}
+	r := &resolver{offline: true, store: store, manager: m, catalog: catalog{offline: true, store: store, manager: m}}
+
+	tests := []struct {
+		plugin    string
+		spec      string
+		installed []string
+		want      string
+	}{
+		{plugin: "python", spec: "3.12", want: "3.12.8"},
+		{plugin: "python", spec: "latest", want: "3.13.0"},
+		{plugin: "python", spec: "3.11", installed: []string{"3.11.11"}, want: "3.11.11"},
+		{plugin: "deno", spec: "latest", installed: []string{"2.1.4", "2.0.6"}, want: "2.1.4"},
+		// local specs never need a catalog
+		{plugin: "bun", spec: "system", want: "system"},
+		{plugin: "python", spec: "path:/opt/python/3.14", want: "path:/opt/python/3.14"},
+		{plugin: "python", spec: "ref:v3.14.0", want: "ref:v3.14.0"},
+	}
+	for _, tt := range tests {
+		got, err := r.resolveVersion(tt.plugin, tt.spec, tt.installed)
+		if err != nil {
+			t.Errorf("resolveVersion(%s, %s) error: %v", tt.plugin, tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveVersion(%s, %s) = %s, want %s", tt.plugin, tt.spec, got, tt.want)
+		}
+	}
+
+	if _, err := r.catalog.listAll("bun"); !errors.Is(err, cache.ErrNotCached) {
+		t.Errorf("listAll(bun) offline error = %v, want ErrNotCached", err)
+	}
+	if _, err := r.resolveVersion("bun", "latest", nil); err == nil {
+		t.Errorf("expected an error resolving bun offline with no catalog and nothing installed")
+	}
+}
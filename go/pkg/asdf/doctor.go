@@ -0,0 +1,141 @@
+package asdf
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// shimTools maps plugins to the executable checked with `asdf which`,
+// where it differs from the plugin name
+var shimTools = map[string]string{
+	"nodejs": "node",
+	"golang": "go",
+}
+
+func shimTool(plugin string) string {
+	if tool, ok := shimTools[plugin]; ok {
+		return tool
+	}
+	return plugin
+}
+
+// doctor checks that the shims of every plugin resolve to its home version:
+//   - every executable of the home version has a shim
+//   - asdf which <tool> points into the home version's install directory
+//   - the tool found in PATH is the shim (e.g. not a brew upgrade shadowing it)
+//
+// Plugins with missing or stale shims are reshimmed (in plan mode, the command is shown),
+// and checked again.
+func (r *resolver) doctor(desiredVersions map[string][]string, opts Options) error {
+	m := r.manager
+	shimsDir, err := m.ShimsDir()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nShim health (%s):\n", shimsDir)
+
+	var stale []string
+	for _, plugin := range sortedKeys(desiredVersions) {
+		if problems := checkShims(m, plugin, shimsDir); len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Printf("✗ - %s: %s\n", plugin, p)
+			}
+			stale = append(stale, plugin)
+		}
+	}
+	checkShimsInPath(desiredVersions, shimsDir)
+	if len(stale) == 0 {
+		return nil
+	}
+
+	if opts.DryRun {
+		fmt.Printf("- To recreate stale shims:\n")
+		for _, plugin := range stale {
+			fmt.Printf(" %s\n", CommandLine(m, Reshim, plugin, ""))
+		}
+		return nil
+	}
+	for _, plugin := range stale {
+		if err := Run(m, Reshim, plugin, ""); err != nil {
+			return fmt.Errorf("failed to reshim %s: %w", plugin, err)
+		}
+		if problems := checkShims(m, plugin, shimsDir); len(problems) > 0 {
+			fmt.Printf("✗ - %s: shims still broken after reshim: %s\n", plugin, strings.Join(problems, "; "))
+			continue
+		}
+		fmt.Printf("✓ - %s: shims were recreated\n", plugin)
+	}
+	return nil
+}
+
+// checkShims returns the shim problems of a plugin's home version, if any
+func checkShims(m RuntimeManager, plugin, shimsDir string) []string {
+	home, err := m.Default(plugin)
+	if err != nil {
+		return []string{fmt.Sprintf("no home version: %v", err)}
+	}
+	if isUnmanaged(home) {
+		fmt.Printf("✓ - %s: home version %s is not installed by %s, no shims to check\n", plugin, home, m.Name())
+		return nil
+	}
+	installDir, err := m.Where(plugin, home)
+	if err != nil {
+		return []string{fmt.Sprintf("home version %s is not installed", home)}
+	}
+
+	var problems []string
+	if missing := missingShims(filepath.Join(installDir, "bin"), shimsDir); len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing shims for %s", strings.Join(missing, " ")))
+	}
+	tool := shimTool(plugin)
+	which, err := m.Which(tool)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("%s which %s failed: %v", m.Name(), tool, err))
+	case !strings.HasPrefix(which, installDir+string(filepath.Separator)):
+		problems = append(problems, fmt.Sprintf("%s which %s -> %s, not the home version %s", m.Name(), tool, which, home))
+	}
+	if len(problems) == 0 {
+		fmt.Printf("✓ - %s: shims resolve to the home version %s\n", plugin, home)
+	}
+	return problems
+}
+
+// missingShims returns the executables in binDir without a shim in shimsDir
+func missingShims(binDir, shimsDir string) []string {
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		return nil // nothing to shim (some plugins use another layout)
+	}
+	var missing []string
+	for _, e := range entries {
+		info, err := os.Stat(filepath.Join(binDir, e.Name())) // follows symlinks
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(shimsDir, e.Name())); os.IsNotExist(err) {
+			missing = append(missing, e.Name())
+		}
+	}
+	return missing
+}
+
+// checkShimsInPath warns when a tool found in PATH is not the shim,
+// which happens when the shims directory is missing from PATH,
+// or comes after a directory holding the same tool (e.g. /opt/homebrew/bin)
+func checkShimsInPath(desiredVersions map[string][]string, shimsDir string) {
+	for _, plugin := range sortedKeys(desiredVersions) {
+		tool := shimTool(plugin)
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			fmt.Printf("✗ - %s is not in PATH: add %s to PATH\n", tool, shimsDir)
+			continue
+		}
+		if filepath.Dir(path) != shimsDir {
+			fmt.Printf("△ - %s resolves to %s, not the %s shim: put %s first in PATH\n", tool, path, plugin, shimsDir)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package asdf
+
+import (
+	"errors"
+	"testing"
+)
+
+// localHomeManager has a local home version, which it did not install
+type localHomeManager struct {
+	asdfManager
+	home string
+}
+
+func (m localHomeManager) Default(string) (string, error) { return m.home, nil }
+
+func (localHomeManager) Where(string, string) (string, error) {
+	return "", errors.New("version not installed")
+}
+
+func TestCheckShimsUnmanaged(t *testing.T) {
+	for _, home := range []string{"system", "path:/opt/python/3.14"} {
+		if problems := checkShims(localHomeManager{home: home}, "python", t.TempDir()); len(problems) > 0 {
+			t.Errorf("checkShims(home %s) = %v, want no problems", home, problems)
+		}
+	}
+}
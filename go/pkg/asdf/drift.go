@@ -0,0 +1,109 @@
+package asdf
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/daneroo/dotfiles/go/pkg/version"
+)
+
+// driftKind classifies a difference between desired and installed versions
+type driftKind string
+
+const (
+	// upgrade: a newer version replaces an installed one of the same line
+	upgrade driftKind = "upgrade"
+	// addition: a desired version with no installed predecessor
+	addition driftKind = "addition"
+	// removal: an installed version that nothing desires or supersedes
+	removal driftKind = "removal"
+)
+
+// drift is one difference: From is set for upgrades and removals, To for upgrades and additions
+type drift struct {
+	Kind     driftKind
+	From, To string
+}
+
+func (d drift) String() string {
+	switch d.Kind {
+	case upgrade:
+		return fmt.Sprintf("%s -> %s", d.From, d.To)
+	case addition:
+		return d.To
+	}
+	return d.From
+}
+
+// classifyDrift pairs missing and extraneous versions into upgrades:
+// a missing version upgrades the highest older extraneous version of the same line.
+// The line is the prefix of the spec the version matches ("3.12": 3.12.7 -> 3.12.8),
+// or its major version for "latest" and "lts" (2.1.3 -> 2.1.4, but not 1.2.0 -> 2.1.4).
+// Unpaired missing versions are additions, unpaired extraneous versions are removals.
+//
+//	specs: ["3.12"], missing: [3.12.8 3.13.1], extra: [3.12.6 3.12.7]
+//	-> upgrade 3.12.7 -> 3.12.8, addition 3.13.1, removal 3.12.6
+func classifyDrift(specs, missing, extra []string) []drift {
+	var drifts []drift
+	paired := make(map[string]bool)
+	for _, to := range sortVersions(missing) {
+		line := driftLine(specs, to)
+		from := ""
+		for _, v := range filterAndSortVersions(extra, line) {
+			if !paired[v] && version.Compare(v, to) < 0 {
+				from = v // highest wins, extra is sorted
+			}
+		}
+		if line == "" || from == "" {
+			drifts = append(drifts, drift{Kind: addition, To: to})
+			continue
+		}
+		paired[from] = true
+		drifts = append(drifts, drift{Kind: upgrade, From: from, To: to})
+	}
+	for _, v := range sortVersions(extra) {
+		if !paired[v] {
+			drifts = append(drifts, drift{Kind: removal, From: v})
+		}
+	}
+	return drifts
+}
+
+// driftLine returns the release line of a version: the longest numeric spec it matches,
+// or its major version when there is a "latest" or "lts" spec, or "" (no line: workspace pins)
+func driftLine(specs []string, v string) string {
+	parsed, err := version.Parse(v)
+	if err != nil || len(parsed.Release) == 0 {
+		return ""
+	}
+	line := ""
+	for _, spec := range specs {
+		switch {
+		case isVersionPrefix(spec):
+			if parsed.HasPrefix(spec) && len(spec) > len(line) {
+				line = spec
+			}
+		case line == "":
+			line = strconv.Itoa(parsed.Release[0])
+		}
+	}
+	return line
+}
+
+// superseded returns the versions replaced by an upgrade
+func superseded(drifts []drift) []string {
+	var versions []string
+	for _, d := range drifts {
+		if d.Kind == upgrade {
+			versions = append(versions, d.From)
+		}
+	}
+	return versions
+}
+
+// reportDrift shows the drift of a plugin, e.g. "✗ - upgrade: python 3.12.7 -> 3.12.8"
+func reportDrift(plugin string, drifts []drift) {
+	for _, d := range drifts {
+		fmt.Printf("✗ - %s: %s %s\n", d.Kind, plugin, d)
+	}
+}
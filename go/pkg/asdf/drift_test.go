@@ -0,0 +1,63 @@
+package asdf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyDrift(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		missing []string
+		extra   []string
+		want    []string
+	}{
+		{
+			name:    "patch upgrade",
+			specs:   []string{"3.12", "3.11"},
+			missing: []string{"3.12.8"},
+			extra:   []string{"3.12.7"},
+			want:    []string{"upgrade 3.12.7 -> 3.12.8"},
+		},
+		{
+			name:    "highest older version is upgraded, others removed",
+			specs:   []string{"3.12"},
+			missing: []string{"3.12.8", "3.13.1"},
+			extra:   []string{"3.12.6", "3.12.7", "3.11.9"},
+			want:    []string{"upgrade 3.12.7 -> 3.12.8", "addition 3.13.1", "removal 3.11.9", "removal 3.12.6"},
+		},
+		{
+			name:    "latest upgrades within the major version",
+			specs:   []string{"latest"},
+			missing: []string{"2.1.4"},
+			extra:   []string{"1.46.3", "2.1.3"},
+			want:    []string{"upgrade 2.1.3 -> 2.1.4", "removal 1.46.3"},
+		},
+		{
+			name:    "newer extraneous version is not an upgrade",
+			specs:   []string{"3.12"},
+			missing: []string{"3.12.7"},
+			extra:   []string{"3.12.8"},
+			want:    []string{"addition 3.12.7", "removal 3.12.8"},
+		},
+		{
+			name:    "exact spec has no line to upgrade",
+			specs:   []string{"3.12.8", "3.11"},
+			missing: []string{"3.12.8"},
+			extra:   []string{"3.11.9"},
+			want:    []string{"addition 3.12.8", "removal 3.11.9"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range classifyDrift(tt.specs, tt.missing, tt.extra) {
+				got = append(got, string(d.Kind)+" "+d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("classifyDrift() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package asdf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isLocalSpec reports whether a spec names a version as is, with no catalog to resolve it against:
+//   - "system": the runtime installed outside asdf (e.g. by brew)
+//   - "path:<dir>": a runtime built locally
+//   - "ref:<git-ref>": a runtime asdf builds from a git ref (installed like any version)
+func isLocalSpec(spec string) bool {
+	return spec == "system" || strings.HasPrefix(spec, "path:") || strings.HasPrefix(spec, "ref:")
+}
+
+// isUnmanaged reports whether a version is not installed by the runtime manager
+// (system and path: versions): it is verified instead of installed, and never extraneous
+func isUnmanaged(v string) bool {
+	return v == "system" || strings.HasPrefix(v, "path:")
+}
+
+// resolveLocal returns a local spec as a version, expanding "~/" in paths
+func resolveLocal(spec string) (string, error) {
+	dir, ok := strings.CutPrefix(spec, "path:")
+	if !ok {
+		return spec, nil
+	}
+	dir, err := expandHome(dir)
+	if err != nil {
+		return "", err
+	}
+	return "path:" + dir, nil
+}
+
+// splitUnmanaged separates the versions the runtime manager installs from the unmanaged ones
+func splitUnmanaged(versions []string) (managed, unmanaged []string) {
+	for _, v := range versions {
+		if isUnmanaged(v) {
+			unmanaged = append(unmanaged, v)
+		} else {
+			managed = append(managed, v)
+		}
+	}
+	return managed, unmanaged
+}
+
+// verifyUnmanaged checks that an unmanaged version exists:
+//   - "system": the plugin's tool is found in PATH, outside the shims directory
+//   - "path:<dir>": the directory exists
+func verifyUnmanaged(m RuntimeManager, plugin, v string) bool {
+	if dir, ok := strings.CutPrefix(v, "path:"); ok {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Printf("✗ - %s: %s does not exist\n", plugin, v)
+			return false
+		}
+		fmt.Printf("✓ - %s: %s exists\n", plugin, v)
+		return true
+	}
+
+	shimsDir, err := m.ShimsDir()
+	if err != nil {
+		fmt.Printf("✗ - %s: %v\n", plugin, err)
+		return false
+	}
+	tool := shimTool(plugin)
+	if path, ok := findInPath(tool, os.Getenv("PATH"), shimsDir); ok {
+		fmt.Printf("✓ - %s: system -> %s\n", plugin, path)
+		return true
+	}
+	fmt.Printf("✗ - %s: system %s not found in PATH (outside %s)\n", plugin, tool, shimsDir)
+	return false
+}
+
+// findInPath looks for an executable in a PATH list, skipping one directory
+func findInPath(tool, pathList, skip string) (string, bool) {
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" || filepath.Clean(dir) == filepath.Clean(skip) {
+			continue
+		}
+		path := filepath.Join(dir, tool)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,27 @@
+package asdf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindInPath(t *testing.T) {
+	shims, brew := t.TempDir(), t.TempDir()
+	for _, dir := range []string{shims, brew} {
+		if err := os.WriteFile(filepath.Join(dir, "python"), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pathList := shims + string(os.PathListSeparator) + brew
+
+	if got, ok := findInPath("python", pathList, shims); !ok || got != filepath.Join(brew, "python") {
+		t.Errorf("findInPath(python) = %q, %v; want the brew python", got, ok)
+	}
+	if _, ok := findInPath("python", shims, shims); ok {
+		t.Errorf("findInPath(python) found the shim, want it skipped")
+	}
+	if _, ok := findInPath("bun", pathList, shims); ok {
+		t.Errorf("findInPath(bun) found a missing tool")
+	}
+}
@@ -0,0 +1,247 @@
+package asdf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RuntimeManager is the tool that installs runtime versions: asdf or mise.
+// Plugins and versions use asdf names ("nodejs", "python"); implementations
+// translate them to their own tool names.
+//
+// Mutations (install, uninstall, set default) are described by Command,
+// so that plan mode can show them, and executed by Run.
+type RuntimeManager interface {
+	// Name is the executable, e.g. "asdf"
+	Name() string
+	// ListPlugins returns the plugins (tools) that versions can be installed for
+	ListPlugins() ([]string, error)
+	// ListInstalled returns the installed versions of a plugin
+	ListInstalled(plugin string) ([]string, error)
+	// ListAvailable returns every version of a plugin that can be installed (uncached)
+	ListAvailable(plugin string) ([]string, error)
+	// Latest returns the latest stable version of a plugin
+	Latest(plugin string) (string, error)
+	// Default returns the home (global) version of a plugin
+	Default(plugin string) (string, error)
+	// Where returns the install directory of a version
+	Where(plugin, version string) (string, error)
+	// Which returns the executable a tool's shim resolves to
+	Which(tool string) (string, error)
+	// ShimsDir returns the directory holding the shims, which must be in PATH
+	ShimsDir() (string, error)
+	// PluginDir returns the git checkout of a plugin, or "" when the plugin
+	// is built into the manager (mise core tools) and has no checkout
+	PluginDir(plugin string) (string, error)
+	// Command returns the command line performing an action
+	Command(action Action, plugin, arg string) []string
+}
+
+// Action is a mutation performed by a RuntimeManager
+type Action int
+
+const (
+	// AddPlugin adds a plugin, arg is its URL (optional)
+	AddPlugin Action = iota
+	// UpdatePlugin updates a plugin, arg is a git ref (optional)
+	UpdatePlugin
+	// RemovePlugin removes a plugin and all its versions
+	RemovePlugin
+	// Install installs version arg
+	Install
+	// Uninstall uninstalls version arg
+	Uninstall
+	// SetDefault sets version arg as the home (global) version
+	SetDefault
+	// Reshim recreates the shims of a plugin
+	Reshim
+)
+
+// NewManager returns the RuntimeManager for a name: "asdf" (the default, for "") or "mise"
+func NewManager(name string) (RuntimeManager, error) {
+	switch name {
+	case "", "asdf":
+		return asdfManager{}, nil
+	case "mise":
+		return miseManager{}, nil
+	}
+	return nil, fmt.Errorf("unknown runtime manager %q (asdf or mise)", name)
+}
+
+// runner is implemented by managers that perform actions themselves,
+// rather than by running their command line (fakes, in tests)
+type runner interface {
+	run(action Action, plugin, arg string) error
+}
+
+// Run executes an action, returning its combined output in the error
+func Run(m RuntimeManager, action Action, plugin, arg string) error {
+	if r, ok := m.(runner); ok {
+		return r.run(action, plugin, arg)
+	}
+	args := m.Command(action, plugin, arg)
+	if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w\n%s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}
+
+// CommandLine returns the command line of an action, as shown in plan mode
+func CommandLine(m RuntimeManager, action Action, plugin, arg string) string {
+	return strings.Join(m.Command(action, plugin, arg), " ")
+}
+
+// asdfManager drives the asdf CLI (0.16+)
+type asdfManager struct{}
+
+func (asdfManager) Name() string { return "asdf" }
+
+func (asdfManager) ListPlugins() ([]string, error) {
+	out, err := exec.Command("asdf", "plugin", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// ListInstalled runs asdf list <plugin> and cleans up the output:
+// - Removes '*' prefix which marks the default/global version
+// - Example input:  "  21.7.3\n  22.12.0\n *22.12.0"
+// - Example output: ["21.7.3", "22.12.0", "22.12.0"]
+func (asdfManager) ListInstalled(plugin string) ([]string, error) {
+	// BECAUSE: asdf list <plugin> now writes "No compatible versions installed" to stderr
+	// when no versions are installed, we need to capture stderr to handle this case
+	cmd := exec.Command("asdf", "list", plugin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output() // Only captures stdout
+
+	if err != nil {
+		// If stderr contains "No compatible versions installed", return empty slice
+		if strings.Contains(stderr.String(), "No compatible versions installed") {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list %s versions: %w\nstderr: %s", plugin, err, stderr.String())
+	}
+
+	var versions []string
+	for _, v := range strings.Fields(string(out)) {
+		v = strings.TrimPrefix(v, "*")
+		v = strings.TrimSpace(v)
+		if v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+func (asdfManager) ListAvailable(plugin string) ([]string, error) {
+	cmd := exec.Command("asdf", "list", "all", plugin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s versions: %w\nstderr: %s\nNote: Might be due to GitHub API rate limiting (60 requests/hour)\nCommand:\nasdf list all %s", plugin, err, stderr.String(), plugin)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func (asdfManager) Latest(plugin string) (string, error) {
+	cmd := exec.Command("asdf", "latest", plugin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output() // Only captures stdout
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest %s version: %w\nstderr: %s\nNote: Might be due to GitHub API rate limiting (60 requests/hour)\nCommand:\nasdf latest %s", plugin, err, stderr.String(), plugin)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (asdfManager) Default(plugin string) (string, error) {
+	out, err := exec.Command("asdf", "current", "--no-header", plugin).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current %s version: %w", plugin, err)
+	}
+	fields := strings.Fields(string(out)) // [plugin] [version] [source]
+	if len(fields) < 2 {
+		return "", fmt.Errorf("unexpected asdf current %s output: %q", plugin, out)
+	}
+	return fields[1], nil
+}
+
+func (asdfManager) Where(plugin, version string) (string, error) {
+	out, err := exec.Command("asdf", "where", plugin, version).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (asdfManager) Which(tool string) (string, error) {
+	out, err := exec.Command("asdf", "which", tool).Output()
+	if err != nil {
+		return "", fmt.Errorf("asdf which %s: %w", tool, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// dataDir is $ASDF_DATA_DIR, defaulting to ~/.asdf
+func (asdfManager) dataDir() (string, error) {
+	if dataDir := os.Getenv("ASDF_DATA_DIR"); dataDir != "" {
+		return dataDir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".asdf"), nil
+}
+
+func (m asdfManager) ShimsDir() (string, error) {
+	dataDir, err := m.dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "shims"), nil
+}
+
+// PluginDir is $ASDF_DATA_DIR/plugins/<plugin>
+func (m asdfManager) PluginDir(plugin string) (string, error) {
+	dataDir, err := m.dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "plugins", plugin), nil
+}
+
+func (asdfManager) Command(action Action, plugin, arg string) []string {
+	switch action {
+	case AddPlugin:
+		return optional([]string{"asdf", "plugin", "add", plugin}, arg)
+	case UpdatePlugin:
+		return optional([]string{"asdf", "plugin", "update", plugin}, arg)
+	case RemovePlugin:
+		return []string{"asdf", "plugin", "remove", plugin}
+	case Install:
+		return []string{"asdf", "install", plugin, arg}
+	case Uninstall:
+		return []string{"asdf", "uninstall", plugin, arg}
+	case SetDefault:
+		return []string{"asdf", "set", "--home", plugin, arg}
+	case Reshim:
+		return []string{"asdf", "reshim", plugin}
+	}
+	panic(fmt.Sprintf("unknown action %d", action))
+}
+
+// optional appends arg to args, unless it is empty
+func optional(args []string, arg string) []string {
+	if arg != "" {
+		args = append(args, arg)
+	}
+	return args
+}
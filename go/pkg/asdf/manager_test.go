@@ -0,0 +1,50 @@
+package asdf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCommandLine(t *testing.T) {
+	tests := []struct {
+		manager RuntimeManager
+		action  Action
+		plugin  string
+		arg     string
+		want    string
+	}{
+		{asdfManager{}, Install, "python", "3.12.8", "asdf install python 3.12.8"},
+		{asdfManager{}, SetDefault, "nodejs", "22.12.0", "asdf set --home nodejs 22.12.0"},
+		{asdfManager{}, AddPlugin, "bun", "", "asdf plugin add bun"},
+		{asdfManager{}, UpdatePlugin, "bun", "v1.0.0", "asdf plugin update bun v1.0.0"},
+		{miseManager{}, Install, "nodejs", "22.12.0", "mise install node@22.12.0"},
+		{miseManager{}, Uninstall, "python", "3.12.7", "mise uninstall python@3.12.7"},
+		{miseManager{}, SetDefault, "nodejs", "22.12.0", "mise use --global node@22.12.0"},
+		{miseManager{}, UpdatePlugin, "bun", "v1.0.0", "mise plugins update bun#v1.0.0"},
+	}
+	for _, tt := range tests {
+		if got := CommandLine(tt.manager, tt.action, tt.plugin, tt.arg); got != tt.want {
+			t.Errorf("CommandLine(%s, %d, %s, %s) = %q, want %q", tt.manager.Name(), tt.action, tt.plugin, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestMissingShims(t *testing.T) {
+	binDir, shimsDir := t.TempDir(), t.TempDir()
+	for name, mode := range map[string]os.FileMode{"node": 0755, "npm": 0755, "README": 0644} {
+		if err := os.WriteFile(filepath.Join(binDir, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(shimsDir, "node"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := missingShims(binDir, shimsDir); !reflect.DeepEqual(got, []string{"npm"}) {
+		t.Errorf("missingShims = %v, want [npm]", got)
+	}
+	if got := missingShims(filepath.Join(binDir, "nope"), shimsDir); got != nil {
+		t.Errorf("missingShims(no bin dir) = %v, want nil", got)
+	}
+}
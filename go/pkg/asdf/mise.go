@@ -0,0 +1,172 @@
+package asdf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// miseTools maps asdf plugin names to mise tool names, where they differ
+var miseTools = map[string]string{
+	"nodejs": "node",
+	"golang": "go",
+}
+
+// miseManager drives the mise CLI. Most runtimes are mise core tools:
+// they need no plugin, and are reported as installed plugins.
+type miseManager struct{}
+
+func (miseManager) Name() string { return "mise" }
+
+// tool returns the mise name of an asdf plugin
+func (miseManager) tool(plugin string) string {
+	if tool, ok := miseTools[plugin]; ok {
+		return tool
+	}
+	return plugin
+}
+
+// ListPlugins returns the core tools and installed plugins, with asdf names
+func (miseManager) ListPlugins() ([]string, error) {
+	out, err := exec.Command("mise", "plugins", "ls", "--core", "--user").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	var plugins []string
+	for _, tool := range strings.Fields(string(out)) {
+		for plugin, t := range miseTools {
+			if t == tool {
+				tool = plugin
+			}
+		}
+		plugins = append(plugins, tool)
+	}
+	return plugins, nil
+}
+
+func (m miseManager) ListInstalled(plugin string) ([]string, error) {
+	out, err := exec.Command("mise", "ls", "--installed", "--json", m.tool(plugin)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s versions: %w", plugin, err)
+	}
+	var installed []struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(out, &installed); err != nil {
+		return nil, fmt.Errorf("parsing mise ls %s: %w", m.tool(plugin), err)
+	}
+	versions := []string{}
+	for _, v := range installed {
+		versions = append(versions, v.Version)
+	}
+	return versions, nil
+}
+
+func (m miseManager) ListAvailable(plugin string) ([]string, error) {
+	out, err := exec.Command("mise", "ls-remote", m.tool(plugin)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s versions: %w\nCommand:\nmise ls-remote %s", plugin, err, m.tool(plugin))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func (m miseManager) Latest(plugin string) (string, error) {
+	out, err := exec.Command("mise", "latest", m.tool(plugin)).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest %s version: %w\nCommand:\nmise latest %s", plugin, err, m.tool(plugin))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Default returns the first active version (mise current <tool> lists all of them)
+func (m miseManager) Default(plugin string) (string, error) {
+	out, err := exec.Command("mise", "current", m.tool(plugin)).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current %s version: %w", plugin, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("no current %s version", plugin)
+	}
+	return fields[0], nil
+}
+
+func (m miseManager) Where(plugin, version string) (string, error) {
+	out, err := exec.Command("mise", "where", m.tool(plugin)+"@"+version).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (miseManager) Which(tool string) (string, error) {
+	out, err := exec.Command("mise", "which", tool).Output()
+	if err != nil {
+		return "", fmt.Errorf("mise which %s: %w", tool, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// dataDir is $MISE_DATA_DIR, defaulting to ~/.local/share/mise
+func (miseManager) dataDir() (string, error) {
+	if dataDir := os.Getenv("MISE_DATA_DIR"); dataDir != "" {
+		return dataDir, nil
+	}
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "mise"), nil
+}
+
+func (m miseManager) ShimsDir() (string, error) {
+	dataDir, err := m.dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "shims"), nil
+}
+
+// PluginDir is $MISE_DATA_DIR/plugins/<tool>. Core tools have no checkout.
+func (m miseManager) PluginDir(plugin string) (string, error) {
+	dataDir, err := m.dataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(dataDir, "plugins", m.tool(plugin))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return "", nil
+	}
+	return dir, nil
+}
+
+func (m miseManager) Command(action Action, plugin, arg string) []string {
+	tool := m.tool(plugin)
+	switch action {
+	case AddPlugin:
+		return optional([]string{"mise", "plugins", "install", tool}, arg)
+	case UpdatePlugin:
+		if arg != "" {
+			tool += "#" + arg
+		}
+		return []string{"mise", "plugins", "update", tool}
+	case RemovePlugin:
+		return []string{"mise", "plugins", "uninstall", tool}
+	case Install:
+		return []string{"mise", "install", tool + "@" + arg}
+	case Uninstall:
+		return []string{"mise", "uninstall", tool + "@" + arg}
+	case SetDefault:
+		return []string{"mise", "use", "--global", tool + "@" + arg}
+	case Reshim:
+		return []string{"mise", "reshim"} // all tools at once
+	}
+	panic(fmt.Sprintf("unknown action %d", action))
+}
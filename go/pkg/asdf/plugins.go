@@ -3,17 +3,14 @@ package asdf
 import (
 	"fmt"
 	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
-)
 
-// getActualPlugins returns the list of currently installed plugins
-func getActualPlugins() ([]string, error) {
-	out, err := exec.Command("asdf", "plugin", "list").Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to list plugins: %w", err)
-	}
-	return strings.Fields(string(out)), nil
-}
+	"github.com/daneroo/dotfiles/go/pkg/collect"
+	"github.com/daneroo/dotfiles/go/pkg/config"
+)
 
 // reconcilePlugins determines which plugins need to be installed/removed
 func reconcilePlugins(desired []string, actual []string) (missing, extra []string) {
@@ -44,44 +41,254 @@ func reconcilePlugins(desired []string, actual []string) (missing, extra []strin
 	return missing, extra
 }
 
-// performPluginActions handles installation, updates, and removal hints for plugins
-func performPluginActions(desiredVersions map[string][]string, missing, extra []string) error {
-	// Install missing plugins
+// git runs a git command in a plugin checkout, returning its trimmed output
+func git(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s (%s): %w", strings.Join(args, " "), dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// normalizeURL makes plugin repository URLs comparable:
+// "https://github.com/asdf-community/asdf-python.git/" == "https://github.com/asdf-community/asdf-python"
+func normalizeURL(url string) string {
+	url = strings.TrimSuffix(strings.TrimSpace(url), "/")
+	return strings.ToLower(strings.TrimSuffix(url, ".git"))
+}
+
+// isAtRef reports whether the plugin checkout's HEAD is the commit that ref
+// (a branch, tag or sha) points to. A branch is the remote-tracking one (origin/<branch>),
+// as of the last fetch: the local branch may be behind it.
+func isAtRef(dir, ref string) (bool, error) {
+	head, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	want, err := git(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+ref+"^{commit}")
+	if err != nil {
+		// not a branch: a tag or sha
+		want, err = git(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	}
+	if err != nil {
+		// unknown locally: the ref has not been fetched yet
+		return false, nil
+	}
+	return head == want, nil
+}
+
+// performPluginActions handles installation, updates, and removal hints for plugins:
+//   - missing plugins are added, from their configured URL if any
+//   - plugins whose origin differs from the configured URL are pointed to it
+//   - pinned plugins (with a configured ref) are checked out at that ref, and never updated past it
+//   - other plugins are updated when their upstream branch has new commits
+//
+// Plugin checkouts are inspected with git rather than by running asdf plugin update,
+// so plan mode reports available updates without changing anything.
+// When applying, plugin checkouts are fetched concurrently; every change is made sequentially.
+// In plan mode and offline, nothing is fetched: the network is not touched, and updates
+// are detected against the last fetched remote refs.
+// Plugins without a checkout (mise core tools) have nothing to update.
+func performPluginActions(m RuntimeManager, desiredVersions map[string][]string, missing, extra []string, opts Options) error {
+	reason := opts.noInstallReason()
 	for _, plugin := range missing {
-		fmt.Printf("✗ - asdf plugin %s is missing. Installing\n", plugin)
-		if err := exec.Command("asdf", "plugin", "add", plugin).Run(); err != nil {
+		url := opts.Sources[plugin].URL
+		if reason != "" {
+			fmt.Printf("✗ - %s plugin %s is missing (%s, not installing)\n", m.Name(), plugin, reason)
+			fmt.Printf(" %s\n", CommandLine(m, AddPlugin, plugin, url))
+			continue
+		}
+		fmt.Printf("✗ - %s plugin %s is missing. Installing\n", m.Name(), plugin)
+		if err := Run(m, AddPlugin, plugin, url); err != nil {
 			return fmt.Errorf("failed to install plugin %s: %w", plugin, err)
 		}
-		fmt.Printf("✓ - asdf plugin %s is installed\n", plugin)
+		fmt.Printf("✓ - %s plugin %s is installed\n", m.Name(), plugin)
 	}
 
-	// Update all plugins that should be installed (including newly installed ones)
-	// Note: missing plugins have been installed above, so we can update them too
-	for plugin := range desiredVersions {
-		// There is no way to only "check for updates" for a plugin
-		// so we just update it and parse the output for status
-		updateOut, err := exec.Command("asdf", "plugin", "update", plugin).CombinedOutput()
+	// Check all plugins that should be installed (including newly installed ones)
+	dirs := make(map[string]string)
+	var checkouts []string
+	for _, plugin := range sortedKeys(desiredVersions) {
+		if reason != "" && slices.Contains(missing, plugin) {
+			continue
+		}
+		dir, err := m.PluginDir(plugin)
 		if err != nil {
-			return fmt.Errorf("failed to update plugin %s: %w", plugin, err)
+			return err
+		}
+		if dir == "" {
+			fmt.Printf("✓ - %s is a %s core tool\n", plugin, m.Name())
+			continue
+		}
+		if err := checkPluginSource(plugin, dir, opts.Sources[plugin], reason); err != nil {
+			return err
+		}
+		dirs[plugin] = dir
+		checkouts = append(checkouts, plugin)
+	}
+
+	switch {
+	case len(checkouts) == 0:
+	case reason == "":
+		fetchPlugins(checkouts, dirs)
+	default:
+		fmt.Printf("△ - %s plugins are checked against their last fetched refs (%s, not fetching)\n", m.Name(), reason)
+	}
+
+	for _, plugin := range checkouts {
+		if ref := opts.Sources[plugin].Ref; ref != "" {
+			if err := pinPlugin(m, plugin, dirs[plugin], ref, reason); err != nil {
+				return err
+			}
+			continue
 		}
-		output := string(updateOut)
-		if strings.Contains(output, "Already on 'master'") ||
-			strings.Contains(output, "Your branch is up to date") {
-			fmt.Printf("✓ - %s plugin is installed and up to date\n", plugin)
-		} else {
-			fmt.Printf("✓ - %s plugin is installed and was updated\n", plugin)
-			fmt.Printf("%s\n", output)
+		if err := updatePlugin(m, plugin, dirs[plugin], reason); err != nil {
+			return err
 		}
 	}
 
-	// Remove extraneous plugins (hint)
+	showExtraPlugins(m, extra)
+	return nil
+}
+
+// fetchPlugins fetches the remote refs of the plugin checkouts concurrently (see collect.Run).
+// A failed fetch is not an error: updates are detected against the last fetched refs.
+func fetchPlugins(plugins []string, dirs map[string]string) {
+	errs := make([]error, len(plugins))
+	var steps []collect.Step
+	for i, plugin := range plugins {
+		steps = append(steps, collect.Step{Name: "git fetch " + plugin, Run: func() error {
+			_, errs[i] = git(dirs[plugin], "fetch", "--quiet", "--tags", "origin")
+			return nil
+		}})
+	}
+	collect.Run(collect.Workers, steps...)
+	for i, plugin := range plugins {
+		if errs[i] != nil {
+			fmt.Printf("△ - %s plugin: fetch failed, using the last fetched refs: %v\n", plugin, errs[i])
+		}
+	}
+}
+
+// upstreamStatus compares a plugin checkout's HEAD to its upstream branch
+// (e.g. origin/master), returning the upstream and how many commits HEAD is behind
+func upstreamStatus(dir string) (upstream string, behind int, err error) {
+	upstream, err = git(dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		// detached HEAD (e.g. previously pinned): compare to the remote default branch
+		if upstream, err = git(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err != nil {
+			return "", 0, fmt.Errorf("%s has no upstream branch: %w", dir, err)
+		}
+	}
+	count, err := git(dir, "rev-list", "--count", "HEAD.."+upstream)
+	if err != nil {
+		return "", 0, err
+	}
+	behind, err = strconv.Atoi(count)
+	if err != nil {
+		return "", 0, fmt.Errorf("git rev-list --count (%s): %w", dir, err)
+	}
+	return upstream, behind, nil
+}
+
+// updatePlugin updates a plugin if its upstream branch has new commits.
+// When skipReason is set (plan, offline), it only reports the available update.
+// A checkout without an upstream (local clone, renamed remote) is reported, not an error.
+func updatePlugin(m RuntimeManager, plugin, dir, skipReason string) error {
+	upstream, behind, err := upstreamStatus(dir)
+	if err != nil {
+		fmt.Printf("✗ - %s plugin: cannot check for updates: %v\n", plugin, err)
+		return nil
+	}
+	if behind == 0 {
+		fmt.Printf("✓ - %s plugin is installed and up to date (%s)\n", plugin, upstream)
+		return nil
+	}
+	if skipReason != "" {
+		fmt.Printf("△ - %s plugin update available: %d commits behind %s (%s, not updating)\n", plugin, behind, upstream, skipReason)
+		fmt.Printf(" %s\n", CommandLine(m, UpdatePlugin, plugin, ""))
+		return nil
+	}
+	if err := Run(m, UpdatePlugin, plugin, ""); err != nil {
+		return fmt.Errorf("failed to update plugin %s: %w", plugin, err)
+	}
+	fmt.Printf("✓ - %s plugin was updated (%d commits from %s)\n", plugin, behind, upstream)
+	return nil
+}
+
+// checkPluginSource points an installed plugin's origin to the configured URL.
+// When skipReason is set (plan, offline), it only shows the commands.
+// Plugins are not removed and re-added, because that would uninstall all their versions.
+func checkPluginSource(plugin, dir string, source config.AsdfPluginSource, skipReason string) error {
+	if source.URL == "" {
+		return nil
+	}
+	actual, err := git(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return err
+	}
+	if normalizeURL(actual) == normalizeURL(source.URL) {
+		fmt.Printf("✓ - %s plugin source is %s\n", plugin, source.URL)
+		return nil
+	}
+
+	if skipReason != "" {
+		fmt.Printf("✗ - %s plugin source is %s, configured %s (%s, not changing)\n", plugin, actual, source.URL, skipReason)
+		fmt.Printf(" git -C %s remote set-url origin %s\n", dir, source.URL)
+		return nil
+	}
+	fmt.Printf("✗ - %s plugin source is %s, configured %s. Switching\n", plugin, actual, source.URL)
+	if _, err := git(dir, "remote", "set-url", "origin", source.URL); err != nil {
+		return err
+	}
+	if _, err := git(dir, "fetch", "--quiet", "origin"); err != nil {
+		return err
+	}
+	fmt.Printf("✓ - %s plugin source is %s\n", plugin, source.URL)
+	return nil
+}
+
+// pinPlugin checks out a pinned plugin at its ref, if it is not already there.
+// When skipReason is set (plan, offline), it only shows the command.
+func pinPlugin(m RuntimeManager, plugin, dir, ref, skipReason string) error {
+	at, err := isAtRef(dir, ref)
+	if err != nil {
+		return err
+	}
+	if at {
+		fmt.Printf("✓ - %s plugin is pinned at %s\n", plugin, ref)
+		return nil
+	}
+	if skipReason != "" {
+		fmt.Printf("✗ - %s plugin is not at its pinned ref %s (%s, not updating)\n", plugin, ref, skipReason)
+		fmt.Printf(" %s\n", CommandLine(m, UpdatePlugin, plugin, ref))
+		return nil
+	}
+	fmt.Printf("✗ - %s plugin is not at its pinned ref %s. Updating\n", plugin, ref)
+	if err := Run(m, UpdatePlugin, plugin, ref); err != nil {
+		return fmt.Errorf("failed to update plugin %s to %s: %w", plugin, ref, err)
+	}
+	fmt.Printf("✓ - %s plugin is pinned at %s\n", plugin, ref)
+	return nil
+}
+
+// showExtraPlugins shows removal hints for extraneous plugins
+func showExtraPlugins(m RuntimeManager, extra []string) {
 	if len(extra) > 0 {
 		fmt.Printf("✗ - Extraneous plugins found:\n")
 		for _, plugin := range extra {
 			fmt.Printf("- To remove %s plugin (and all installed versions):\n", plugin)
-			fmt.Printf(" asdf plugin remove %s\n", plugin)
+			fmt.Printf(" %s\n", CommandLine(m, RemovePlugin, plugin, ""))
 		}
 	}
+}
 
-	return nil
+// sortedKeys returns the plugins of a plugin -> versions map, sorted
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,142 @@
+package asdf
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://github.com/asdf-community/asdf-python.git", "https://github.com/asdf-community/asdf-python", true},
+		{"https://github.com/asdf-community/asdf-python/", "https://GitHub.com/asdf-community/asdf-python", true},
+		{"https://github.com/asdf-community/asdf-python", "https://github.com/danhper/asdf-python", false},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.a) == normalizeURL(tt.b); got != tt.same {
+			t.Errorf("normalizeURL(%q) == normalizeURL(%q): got %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
+
+func TestIsAtRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dataDir := t.TempDir()
+	t.Setenv("ASDF_DATA_DIR", dataDir)
+	dir := filepath.Join(dataDir, "plugins", "python")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "--quiet")
+	run("commit", "--quiet", "--allow-empty", "-m", "one")
+	run("tag", "v1.0.0")
+	run("commit", "--quiet", "--allow-empty", "-m", "two")
+
+	for ref, want := range map[string]bool{"HEAD": true, "v1.0.0": false, "v9.9.9": false} {
+		got, err := isAtRef(dir, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("isAtRef(%q) = %v, want %v", ref, got, want)
+		}
+	}
+	run("checkout", "--quiet", "v1.0.0")
+	if got, _ := isAtRef(dir, "v1.0.0"); !got {
+		t.Errorf("isAtRef(v1.0.0) after checkout = false, want true")
+	}
+}
+
+func TestIsAtRefBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote, dir := t.TempDir(), filepath.Join(t.TempDir(), "python")
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git(remote, "init", "--quiet")
+	git(remote, "commit", "--quiet", "--allow-empty", "-m", "one")
+	git(remote, "clone", "--quiet", remote, dir)
+	branch := git(dir, "rev-parse", "--abbrev-ref", "HEAD")
+
+	if got, _ := isAtRef(dir, branch); !got {
+		t.Errorf("isAtRef(%s) after clone = false, want true", branch)
+	}
+	// the local branch is behind origin once fetched: not at the ref
+	git(remote, "commit", "--quiet", "--allow-empty", "-m", "two")
+	git(dir, "fetch", "--quiet", "origin")
+	if got, _ := isAtRef(dir, branch); got {
+		t.Errorf("isAtRef(%s) behind origin/%s = true, want false", branch, branch)
+	}
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote := t.TempDir()
+	dataDir := t.TempDir()
+	t.Setenv("ASDF_DATA_DIR", dataDir)
+	dir := filepath.Join(dataDir, "plugins", "bun")
+	git := func(dir string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git(remote, "init", "--quiet")
+	git(remote, "commit", "--quiet", "--allow-empty", "-m", "one")
+	git(dataDir, "clone", "--quiet", remote, dir)
+
+	if _, behind, err := upstreamStatus(dir); err != nil || behind != 0 {
+		t.Fatalf("upstreamStatus after clone = %d, %v; want 0, nil", behind, err)
+	}
+	git(remote, "commit", "--quiet", "--allow-empty", "-m", "two")
+	git(remote, "commit", "--quiet", "--allow-empty", "-m", "three")
+	if _, behind, _ := upstreamStatus(dir); behind != 0 {
+		t.Errorf("upstreamStatus before fetch = %d, want 0", behind)
+	}
+	git(dir, "fetch", "--quiet", "origin")
+	if _, behind, _ := upstreamStatus(dir); behind != 2 {
+		t.Errorf("upstreamStatus after fetch = %d, want 2", behind)
+	}
+}
+
+func TestUpdatePluginWithoutUpstream(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{{"init", "--quiet"}, {"commit", "--quiet", "--allow-empty", "-m", "one"}} {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if _, _, err := upstreamStatus(dir); err == nil {
+		t.Fatalf("upstreamStatus of a local repository: want an error")
+	}
+	// the other plugins must still be reconciled
+	if err := updatePlugin(asdfManager{}, "bun", dir, ""); err != nil {
+		t.Errorf("updatePlugin() = %v, want nil", err)
+	}
+}
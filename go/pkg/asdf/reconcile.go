@@ -4,22 +4,71 @@ package asdf
 import (
 	"fmt"
 	"os/exec"
+
+	"github.com/daneroo/dotfiles/go/pkg/config"
 )
 
-// Reconcile performs a complete reconciliation cycle for asdf:
-// 1. Check if asdf is installed
+// Options controls how Reconcile resolves versions and which actions it takes
+type Options struct {
+	// Offline resolves version specs purely from cached catalogs and installed versions,
+	// and skips every action that needs the network (plugin add/update, installs)
+	Offline bool
+	// Locked maps plugins to the exact versions recorded in the lock file.
+	// When set, these versions are installed instead of resolving specs;
+	// plugins left out (not locked, or their specs changed since) resolve their specs
+	Locked map[string][]string
+	// DryRun (plan mode) shows the commands that would install, uninstall
+	// or change versions, without running them
+	DryRun bool
+	// Retention holds the retention policy of plugins that declare one
+	Retention map[string]config.AsdfRetention
+	// Workspace lists directories scanned for project version files
+	// (.tool-versions, .nvmrc, .python-version); their pinned versions
+	// are installed along with the configured ones
+	Workspace []string
+	// Sources holds the plugin URL and git ref of plugins that declare one
+	Sources map[string]config.AsdfPluginSource
+	// Upgrade uninstalls the versions superseded by a newer patch of the same line
+	// (e.g. 3.12.7 once "3.12" resolves to 3.12.8), for plugins without a retention policy
+	Upgrade bool
+	// Manager is the runtime manager driven by the asdf specs: "asdf" (default) or "mise"
+	Manager string
+}
+
+// noInstallReason returns why versions and plugins cannot be installed in this run
+// ("offline" or "plan"), or "" when they can
+func (o Options) noInstallReason() string {
+	switch {
+	case o.DryRun:
+		return "plan"
+	case o.Offline:
+		return "offline"
+	}
+	return ""
+}
+
+// Reconcile performs a complete reconciliation cycle for asdf (or mise, see Options.Manager):
+// 1. Check if the runtime manager is installed
 // 2. Get actual state (installed plugins)
 // 3. Compare with desired state
-// 4. Take actions to reconcile differences
-func Reconcile(desiredVersions map[string][]string) error {
-	// Check if asdf is installed
-	if err := exec.Command("command", "-v", "asdf").Run(); err != nil {
-		return fmt.Errorf("asdf is not installed")
+// 4. Collect the installed versions and catalogs of every plugin, concurrently
+// 5. Take actions to reconcile differences, one plugin at a time
+// 6. Check the shims (see doctor)
+func Reconcile(desiredVersions map[string][]string, opts Options) error {
+	r, err := newResolver(opts)
+	if err != nil {
+		return err
+	}
+	m := r.manager
+
+	// Check if the runtime manager is installed
+	if _, err := exec.LookPath(m.Name()); err != nil {
+		return fmt.Errorf("%s is not installed", m.Name())
 	}
-	fmt.Printf("✓ - asdf is installed\n")
+	fmt.Printf("✓ - %s is installed\n", m.Name())
 
 	// Get actual state of installed plugins
-	actualPlugins, err := getActualPlugins()
+	actualPlugins, err := m.ListPlugins()
 	if err != nil {
 		return err
 	}
@@ -32,18 +81,94 @@ func Reconcile(desiredVersions map[string][]string) error {
 
 	// Determine required actions
 	missing, extra := reconcilePlugins(desiredPlugins, actualPlugins)
+	if extra, err = removablePlugins(m, extra); err != nil {
+		return err
+	}
 
 	// Perform all plugin actions
-	if err := performPluginActions(desiredVersions, missing, extra); err != nil {
+	if err := performPluginActions(m, desiredVersions, missing, extra, opts); err != nil {
+		return err
+	}
+
+	// Collect the versions pinned by projects in the workspace
+	var pinned references
+	if len(opts.Workspace) > 0 {
+		if pinned, err = r.references(opts.Workspace); err != nil {
+			return err
+		}
+		reportWorkspace(opts.Workspace, pinned, desiredVersions)
+	}
+
+	// Collect the installed versions and catalogs of every plugin concurrently
+	if err := r.collectState(desiredVersions, opts.Locked); err != nil {
 		return err
 	}
 
 	// Show version resolution
 	for plugin, specs := range desiredVersions {
-		if err := reconcileVersionsForPlugin(plugin, specs); err != nil {
+		var locked []string
+		if opts.Locked != nil {
+			var ok bool
+			if locked, ok = opts.Locked[plugin]; !ok {
+				fmt.Printf("△ - lock drift: asdf plugin %s is not locked for its specs, resolving them\n", plugin)
+			}
+		}
+		if err := reconcileVersionsForPlugin(r, plugin, specs, locked, pinned.pins(plugin), opts); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	// Verify the shims resolve to the home versions
+	return r.doctor(desiredVersions, opts)
+}
+
+// removablePlugins filters out the extraneous plugins without a checkout:
+// mise core tools are always available, and cannot be removed
+func removablePlugins(m RuntimeManager, extra []string) ([]string, error) {
+	var removable []string
+	for _, plugin := range extra {
+		dir, err := m.PluginDir(plugin)
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			removable = append(removable, plugin)
+		}
+	}
+	return removable, nil
+}
+
+// Installed returns the installed versions of a plugin
+func Installed(plugin string, opts Options) ([]string, error) {
+	m, err := NewManager(opts.Manager)
+	if err != nil {
+		return nil, err
+	}
+	return m.ListInstalled(plugin)
+}
+
+// Resolve resolves the version specs of every plugin to concrete versions,
+// without installing anything. The result is what Reconcile would install,
+// and is recorded by `checkdeps lock`.
+func Resolve(desiredVersions map[string][]string, opts Options) (map[string][]string, error) {
+	r, err := newResolver(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.collectState(desiredVersions, nil); err != nil {
+		return nil, err
+	}
+	resolved := make(map[string][]string)
+	for plugin, specs := range desiredVersions {
+		installed, err := r.listInstalled(plugin)
+		if err != nil {
+			return nil, err
+		}
+		versions, err := r.resolveSpecs(plugin, specs, installed)
+		if err != nil {
+			return nil, err
+		}
+		resolved[plugin] = versions
+	}
+	return resolved, nil
 }
@@ -0,0 +1,210 @@
+package asdf
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/daneroo/dotfiles/go/pkg/config"
+	"github.com/daneroo/dotfiles/go/pkg/version"
+)
+
+// retain decides which extraneous versions a retention policy keeps.
+// It returns the kept versions with the reason they are kept, and the versions to remove.
+//
+// For every spec, the "current" version is the highest desired version matching
+// the spec's prefix ("latest" and "lts" match every version). The KeepPrevious
+// highest extraneous versions below it, and matching the same prefix, are kept:
+//
+//	specs: ["3.12"], desired: [3.12.8], extra: [3.12.6 3.12.7 3.11.9], keep_previous: 1
+//	-> keep 3.12.7, remove 3.12.6 and 3.11.9
+//
+// Versions pinned by a project version file (refs, see referencedVersions) are always kept.
+func retain(policy config.AsdfRetention, specs, desired, extra []string, refs map[string][]string) (keep map[string]string, remove []string) {
+	keep = make(map[string]string)
+	for _, v := range extra {
+		if paths, ok := refs[v]; ok {
+			keep[v] = fmt.Sprintf("referenced by %s", strings.Join(paths, ", "))
+		}
+	}
+
+	if policy.KeepPrevious > 0 {
+		for _, spec := range specs {
+			prefix := ""
+			if isVersionPrefix(spec) {
+				prefix = spec
+			}
+			current := filterAndSortVersions(desired, prefix)
+			if len(current) == 0 {
+				continue
+			}
+			newest := current[len(current)-1]
+			previous := filterAndSortVersions(extra, prefix)
+			slices.Reverse(previous) // newest first
+			kept := 0
+			for _, v := range previous {
+				if kept == policy.KeepPrevious {
+					break
+				}
+				if version.Compare(v, newest) < 0 {
+					if _, ok := keep[v]; !ok {
+						keep[v] = fmt.Sprintf("keep_previous: %d for %q", policy.KeepPrevious, spec)
+					}
+					kept++
+				}
+			}
+		}
+	}
+
+	for _, v := range sortVersions(extra) {
+		if _, ok := keep[v]; !ok {
+			remove = append(remove, v)
+		}
+	}
+	return keep, remove
+}
+
+// removeExtraneousVersions handles the versions that are installed but not desired:
+//   - without a retention policy, it only shows the commands to remove them;
+//     with Options.Upgrade, the versions superseded by an upgrade are uninstalled
+//     (once their replacement is installed)
+//   - with a policy, it uninstalls the versions outside the policy
+//
+// Versions are only uninstalled when every desired version is installed: offline or
+// in plan mode, missing versions were not installed, and the home version may still be
+// an extraneous one, so it only shows what would be removed (see uninstallVersions).
+// It reports the disk space reclaimed.
+func (r *resolver) removeExtraneousVersions(plugin string, specs, desired, extra, superseded []string, opts Options) error {
+	if len(extra) == 0 {
+		return nil
+	}
+	policy, ok := opts.Retention[plugin]
+	if !ok {
+		var remove []string
+		if opts.Upgrade {
+			remove = superseded
+		}
+		var hints []string
+		for _, v := range sortVersions(extra) {
+			if !slices.Contains(remove, v) {
+				hints = append(hints, v)
+			}
+		}
+		if len(hints) > 0 {
+			fmt.Printf("✗ - Extraneous %s versions found:\n", plugin)
+			for _, version := range hints {
+				fmt.Printf("- To remove %s version %s:\n", plugin, version)
+				fmt.Printf(" %s\n", CommandLine(r.manager, Uninstall, plugin, version))
+			}
+		}
+		if len(remove) == 0 {
+			return nil
+		}
+		fmt.Printf("✗ - Superseded %s versions: %s\n", plugin, strings.Join(remove, " "))
+		return r.uninstallVersions(plugin, remove, opts)
+	}
+
+	refs, err := r.referencedVersions(plugin, policy.KeepIfReferencedBy, uniqueVersions(append(desired, extra...)))
+	if err != nil {
+		return err
+	}
+	keep, remove := retain(policy, specs, desired, extra, refs)
+
+	for _, v := range sortVersions(extra) {
+		if reason, ok := keep[v]; ok {
+			fmt.Printf("✓ - %s: %s is kept (%s)\n", plugin, v, reason)
+		}
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+
+	fmt.Printf("✗ - Extraneous %s versions outside the retention policy: %s\n", plugin, strings.Join(remove, " "))
+	return r.uninstallVersions(plugin, remove, opts)
+}
+
+// referencedVersions returns the installed versions that projects under the roots pin,
+// with the files pinning them: a partial pin ("3.11") selects the highest installed
+// version matching it, other pins resolve as configured specs do (see resolveVersion).
+// A pin that cannot be resolved is reported and skipped.
+func (r *resolver) referencedVersions(plugin string, roots, installed []string) (map[string][]string, error) {
+	refs, err := r.references(roots)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string][]string)
+	for _, pin := range refs.pins(plugin) {
+		var v string
+		if matches := filterAndSortVersions(installed, pin); isVersionPrefix(pin) && len(matches) > 0 {
+			v = matches[len(matches)-1]
+		} else if v, err = r.resolveVersion(plugin, pin, installed); err != nil {
+			fmt.Printf("△ - %s: cannot resolve %q, pinned by %s: %v\n", plugin, pin, strings.Join(refs[plugin][pin], ", "), err)
+			continue
+		}
+		referenced[v] = append(referenced[v], refs[plugin][pin]...)
+	}
+	return referenced, nil
+}
+
+// uninstallVersions uninstalls versions, reporting the disk space reclaimed.
+// When versions cannot be installed (plan, offline, see Options.noInstallReason), the
+// desired versions may be missing and the home version still an extraneous one:
+// it only shows the commands then. Otherwise missing versions were installed before.
+func (r *resolver) uninstallVersions(plugin string, remove []string, opts Options) error {
+	reason := opts.noInstallReason()
+	var reclaimed int64
+	for _, v := range remove {
+		size := installSize(r.manager, plugin, v)
+		if reason != "" {
+			fmt.Printf(" %s  # %s\n", CommandLine(r.manager, Uninstall, plugin, v), formatBytes(size))
+			reclaimed += size
+			continue
+		}
+		if err := Run(r.manager, Uninstall, plugin, v); err != nil {
+			return fmt.Errorf("failed to uninstall %s version %s: %w", plugin, v, err)
+		}
+		fmt.Printf("  ✓ - %s version %s was uninstalled (%s)\n", plugin, v, formatBytes(size))
+		reclaimed += size
+	}
+	if reason != "" {
+		fmt.Printf("  would remove %d versions, reclaiming %s (%s, not uninstalling)\n", len(remove), formatBytes(reclaimed), reason)
+	} else {
+		fmt.Printf("✓ - %s: reclaimed %s\n", plugin, formatBytes(reclaimed))
+	}
+	return nil
+}
+
+// installSize returns the disk usage of an installed version (asdf where <plugin> <version>),
+// or 0 if it cannot be determined
+func installSize(m RuntimeManager, plugin, version string) int64 {
+	dir, err := m.Where(plugin, version)
+	if err != nil {
+		return 0
+	}
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// formatBytes formats a size in bytes for humans, e.g. "95.2 MB"
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
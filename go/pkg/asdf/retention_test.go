@@ -0,0 +1,150 @@
+package asdf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+	"github.com/daneroo/dotfiles/go/pkg/config"
+)
+
+func TestRetain(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     config.AsdfRetention
+		specs      []string
+		desired    []string
+		extra      []string
+		refs       map[string][]string
+		wantKeep   []string
+		wantRemove []string
+	}{
+		{
+			name:       "keep previous patch per prefix",
+			policy:     config.AsdfRetention{KeepPrevious: 1},
+			specs:      []string{"3.12", "3.11"},
+			desired:    []string{"3.11.11", "3.12.8"},
+			extra:      []string{"3.12.6", "3.12.7", "3.11.9", "3.11.10", "3.10.4"},
+			wantKeep:   []string{"3.11.10", "3.12.7"},
+			wantRemove: []string{"3.10.4", "3.11.9", "3.12.6"},
+		},
+		{
+			name:       "keep previous two for latest",
+			policy:     config.AsdfRetention{KeepPrevious: 2},
+			specs:      []string{"latest"},
+			desired:    []string{"2.1.4"},
+			extra:      []string{"1.46.3", "2.0.6", "2.1.1"},
+			wantKeep:   []string{"2.0.6", "2.1.1"},
+			wantRemove: []string{"1.46.3"},
+		},
+		{
+			name:       "newer extraneous versions are not previous",
+			policy:     config.AsdfRetention{KeepPrevious: 1},
+			specs:      []string{"3.12"},
+			desired:    []string{"3.12.7"},
+			extra:      []string{"3.12.8"},
+			wantRemove: []string{"3.12.8"},
+		},
+		{
+			name:       "referenced versions are kept",
+			policy:     config.AsdfRetention{KeepIfReferencedBy: []string{"~/Code"}},
+			specs:      []string{"3.12"},
+			desired:    []string{"3.12.8"},
+			extra:      []string{"3.9.18", "3.12.7"},
+			refs:       map[string][]string{"3.9.18": {"~/Code/legacy/.tool-versions"}},
+			wantKeep:   []string{"3.9.18"},
+			wantRemove: []string{"3.12.7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, remove := retain(tt.policy, tt.specs, tt.desired, tt.extra, tt.refs)
+			var kept []string
+			for v := range keep {
+				kept = append(kept, v)
+			}
+			if !reflect.DeepEqual(sortVersions(kept), sortVersions(tt.wantKeep)) {
+				t.Errorf("kept = %v, want %v", kept, tt.wantKeep)
+			}
+			if !reflect.DeepEqual(remove, tt.wantRemove) {
+				t.Errorf("removed = %v, want %v", remove, tt.wantRemove)
+			}
+		})
+	}
+}
+
+func TestReferencedVersions(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"ml/.python-version":  "3.11\n",
+		"api/.tool-versions":  "python 3.9.18\n",
+		"cli/.tool-versions":  "python system\n",
+		"web/.python-version": "3.8\n", // nothing installed matches, nor is cached offline
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := cache.Store{Dir: t.TempDir()}
+	r := &resolver{offline: true, store: store, manager: asdfManager{}, scanned: make(map[string]references),
+		catalog: catalog{offline: true, store: store, manager: asdfManager{}}}
+
+	refs, err := r.referencedVersions("python", []string{root}, []string{"3.9.18", "3.11.7", "3.11.9", "3.12.8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"3.11.9": {filepath.Join(root, "ml/.python-version")},
+		"3.9.18": {filepath.Join(root, "api/.tool-versions")},
+		"system": {filepath.Join(root, "cli/.tool-versions")},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("referencedVersions() = %v, want %v", refs, want)
+	}
+}
+
+// recordingManager records the versions it uninstalls, and has no installs to measure
+type recordingManager struct {
+	asdfManager
+	uninstalled *[]string
+}
+
+func (m recordingManager) run(action Action, plugin, arg string) error {
+	if action == Uninstall {
+		*m.uninstalled = append(*m.uninstalled, plugin+" "+arg)
+	}
+	return nil
+}
+
+func (recordingManager) Where(string, string) (string, error) { return "", nil }
+
+func TestRemoveExtraneousVersionsOffline(t *testing.T) {
+	// 3.12.8 is missing, and cannot be installed offline: 3.12.7 may still be the home version
+	var uninstalled []string
+	r := &resolver{manager: recordingManager{uninstalled: &uninstalled}, scanned: make(map[string]references)}
+	opts := Options{
+		Offline:   true,
+		Retention: map[string]config.AsdfRetention{"python": {KeepIfReferencedBy: []string{t.TempDir()}}},
+	}
+	if err := r.removeExtraneousVersions("python", []string{"3.12"}, []string{"3.12.8"}, []string{"3.12.7"}, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(uninstalled) > 0 {
+		t.Errorf("uninstalled %v offline, want none", uninstalled)
+	}
+
+	// online, the missing version was installed first: uninstalling goes ahead
+	opts.Offline = false
+	if err := r.removeExtraneousVersions("python", []string{"3.12"}, []string{"3.12.8"}, []string{"3.12.7"}, nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"python 3.12.7"}; !reflect.DeepEqual(uninstalled, want) {
+		t.Errorf("uninstalled %v, want %v", uninstalled, want)
+	}
+}
@@ -1,50 +1,44 @@
 package asdf
 
 import (
-	"fmt"
-	"regexp"
 	"sort"
-	"strconv"
-	"strings"
+
+	"github.com/daneroo/dotfiles/go/pkg/version"
 )
 
 // filterAndSortVersions takes a list of version strings and a prefix,
-// filters for clean semver versions matching the prefix,
+// filters for stable versions matching the prefix,
 // and returns them sorted in ascending order
 func filterAndSortVersions(versions []string, prefix string) []string {
 	filtered := filterVersions(versions, prefix)
 	return sortVersions(filtered)
 }
 
-// filterVersions returns versions matching the prefix pattern X.Y.Z
+// filterVersions returns stable versions whose release segments start with the prefix.
+// Pre-releases ("3.12.0rc1", "3.12-dev", "1.2.0-beta"), variants ("3.13.0t")
+// and unparseable entries ("miniconda3-4.7.12") are excluded.
+// An empty prefix matches every stable version.
 func filterVersions(versions []string, prefix string) []string {
-	pattern := fmt.Sprintf("^%s(\\.\\d+)*$", regexp.QuoteMeta(prefix))
-	re := regexp.MustCompile(pattern)
-
 	var matches []string
-	for _, v := range versions {
-		if re.MatchString(v) {
-			matches = append(matches, v)
+	for _, s := range versions {
+		v, err := version.Parse(s)
+		if err != nil {
+			continue
+		}
+		if v.IsStable() && (prefix == "" || v.HasPrefix(prefix)) {
+			matches = append(matches, s)
 		}
 	}
 	return matches
 }
 
-// sortVersions sorts version strings in ascending order
+// sortVersions sorts version strings in ascending order,
+// according to version.Compare (pre-releases before final releases)
 func sortVersions(versions []string) []string {
 	sorted := make([]string, len(versions))
 	copy(sorted, versions)
-	sort.Slice(sorted, func(i, j int) bool {
-		a := strings.Split(sorted[i], ".")
-		b := strings.Split(sorted[j], ".")
-		for k := 0; k < len(a) && k < len(b); k++ {
-			ai, _ := strconv.Atoi(a[k])
-			bi, _ := strconv.Atoi(b[k])
-			if ai != bi {
-				return ai < bi
-			}
-		}
-		return len(a) < len(b)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return version.Compare(sorted[i], sorted[j]) < 0
 	})
 	return sorted
 }
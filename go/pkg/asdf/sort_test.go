@@ -36,6 +36,30 @@ func TestFilterVersions(t *testing.T) {
 			prefix:   "3.12",
 			want:     []string{},
 		},
+		{
+			name:     "pep440 pre-releases and free-threaded variants excluded",
+			versions: []string{"3.13.0a1", "3.13.0rc1", "3.13.0", "3.13.0t", "3.13t-dev", "3.13.1"},
+			prefix:   "3.13",
+			want:     []string{"3.13.0", "3.13.1"},
+		},
+		{
+			name:     "prefix matches whole segments only",
+			versions: []string{"3.1.0", "3.12.0", "3.120.1"},
+			prefix:   "3.12",
+			want:     []string{"3.12.0"},
+		},
+		{
+			name:     "calendar versions",
+			versions: []string{"2023.12", "2024.1", "2024.10.1", "2024.2-beta"},
+			prefix:   "2024",
+			want:     []string{"2024.1", "2024.10.1"},
+		},
+		{
+			name:     "unparseable entries are skipped",
+			versions: []string{"miniconda3-4.7.12", "pypy3.10-7.3.17", "3.10.4"},
+			prefix:   "3.10",
+			want:     []string{"3.10.4"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -85,6 +109,31 @@ func TestSortVersions(t *testing.T) {
 			versions: []string{"1.0.0"},
 			want:     []string{"1.0.0"},
 		},
+		{
+			name:     "pep440 pre-releases",
+			versions: []string{"3.13.0", "3.13.0rc1", "3.13.0b2", "3.13.0a1", "3.12.7"},
+			want:     []string{"3.12.7", "3.13.0a1", "3.13.0b2", "3.13.0rc1", "3.13.0"},
+		},
+		{
+			name:     "semver pre-releases and build metadata",
+			versions: []string{"1.2.0+build.5", "1.2.0", "1.2.0-beta.2", "1.2.0-beta", "1.2.0-alpha.10", "1.2.0-alpha.2"},
+			want:     []string{"1.2.0-alpha.2", "1.2.0-alpha.10", "1.2.0-beta", "1.2.0-beta.2", "1.2.0", "1.2.0+build.5"},
+		},
+		{
+			name:     "dev and post releases",
+			versions: []string{"3.12.0.post1", "3.12-dev", "3.12.0"},
+			want:     []string{"3.12-dev", "3.12.0", "3.12.0.post1"},
+		},
+		{
+			name:     "calendar versions",
+			versions: []string{"2024.10", "2023.12.1", "2024.2"},
+			want:     []string{"2023.12.1", "2024.2", "2024.10"},
+		},
+		{
+			name:     "leading v and unparseable entries",
+			versions: []string{"v22.1.0", "system", "v9.11.2"},
+			want:     []string{"system", "v9.11.2", "v22.1.0"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -96,3 +145,11 @@ func TestSortVersions(t *testing.T) {
 		})
 	}
 }
+
+func TestUniqueVersions(t *testing.T) {
+	got := uniqueVersions([]string{"3.12.8", "3.11.11", "3.12.8", "3.13.0rc1"})
+	want := []string{"3.11.11", "3.12.8", "3.13.0rc1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueVersions() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package asdf
+
+import "github.com/daneroo/dotfiles/go/pkg/collect"
+
+// collectState runs the read-only queries of every desired plugin concurrently (see collect.Run):
+//   - its installed versions (asdf list <plugin>)
+//   - its catalog (asdf list all <plugin>), refreshed when missing or stale,
+//     if a version prefix spec resolves against it
+//   - the Node.js release index, for nodejs, whose warnings are reported once collected
+//
+// Versions are then resolved, installed and removed plugin by plugin, sequentially.
+// Locked plugins resolve nothing, so only their installed versions are collected.
+func (r *resolver) collectState(desiredVersions map[string][]string, locked map[string][]string) error {
+	plugins := sortedKeys(desiredVersions)
+	installed := make([][]string, len(plugins))
+	refreshed := make([]error, len(plugins))
+	var nodeWarnings []string
+	var steps []collect.Step
+	for i, plugin := range plugins {
+		steps = append(steps, collect.Step{Name: r.manager.Name() + " list " + plugin, Run: func() (err error) {
+			installed[i], err = r.manager.ListInstalled(plugin)
+			return err
+		}})
+		if _, ok := locked[plugin]; ok {
+			continue
+		}
+		switch {
+		case plugin == "nodejs":
+			steps = append(steps, collect.Step{Name: "nodejs.org index", Run: func() error {
+				// a failure is reported when resolving, warnings once collected
+				r.node.Releases()
+				nodeWarnings = r.node.Warnings()
+				return nil
+			}})
+		case hasPrefixSpec(desiredVersions[plugin]):
+			steps = append(steps, collect.Step{Name: r.manager.Name() + " list all " + plugin, Run: func() error {
+				refreshed[i] = r.catalog.refresh(plugin)
+				return nil
+			}})
+		}
+	}
+	err := collect.Run(collect.Workers, steps...)
+	reportNodeWarnings(nodeWarnings)
+	if err != nil {
+		return err
+	}
+
+	for i, plugin := range plugins {
+		r.installed[plugin] = installed[i]
+		if refreshed[i] != nil {
+			r.catalog.failed[plugin] = refreshed[i]
+		}
+	}
+	return nil
+}
+
+// listInstalled returns the installed versions of a plugin, as collected by collectState
+func (r *resolver) listInstalled(plugin string) ([]string, error) {
+	if versions, ok := r.installed[plugin]; ok {
+		return versions, nil
+	}
+	return r.manager.ListInstalled(plugin)
+}
+
+// hasPrefixSpec reports whether a version prefix spec ("3.12") is among specs
+func hasPrefixSpec(specs []string) bool {
+	for _, spec := range specs {
+		if isVersionPrefix(spec) {
+			return true
+		}
+	}
+	return false
+}
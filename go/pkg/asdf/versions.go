@@ -1,44 +1,58 @@
 package asdf
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"slices"
 	"strings"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/eol"
 )
 
 // reconcileVersionsForPlugin handles the complete version reconciliation for a single plugin:
-// 1. Resolve version specs to concrete versions
-// 2. Get currently installed versions
-// 3. Reconcile differences
-func reconcileVersionsForPlugin(plugin string, specs []string) error {
-	// Resolve version specs
-	fmt.Printf("\nResolving %s versions:\n", plugin)
-	var resolvedVersions []string
-	for _, spec := range specs {
-		resolved, err := resolveVersion(plugin, spec)
+// 1. Get currently installed versions
+// 2. Resolve version specs to concrete versions (or use the locked versions as is)
+// 3. Resolve the versions pinned by workspace projects, which are added to the desired set
+// 4. Reconcile differences
+func reconcileVersionsForPlugin(r *resolver, plugin string, specs, locked, pins []string, opts Options) error {
+	// Get actual installed versions (offline, specs may resolve to them)
+	actual, err := r.listInstalled(plugin)
+	if err != nil {
+		return err
+	}
+
+	var configured []string
+	if locked != nil {
+		configured = uniqueVersions(locked)
+		fmt.Printf("\nLocked %s versions: %s\n", plugin, strings.Join(configured, " "))
+	} else {
+		fmt.Printf("\nResolving %s versions:\n", plugin)
+		configured, err = r.resolveSpecs(plugin, specs, actual)
 		if err != nil {
-			return fmt.Errorf("resolving %s version %q: %w", plugin, spec, err)
+			return err
 		}
-		fmt.Printf("✓ - %s: %s -> %s\n", plugin, spec, resolved)
-		resolvedVersions = append(resolvedVersions, resolved)
+		fmt.Printf("\nResolved %s versions: %s\n", plugin, strings.Join(configured, " "))
 	}
 
-	// Remove duplicates and sort
-	desired := uniqueVersions(resolvedVersions)
-	fmt.Printf("\nResolved %s versions: %s\n", plugin, strings.Join(desired, " "))
+	desired := configured
+	if len(pins) > 0 {
+		fmt.Printf("\nResolving %s versions pinned by the workspace:\n", plugin)
+		desired = uniqueVersions(append(configured, r.resolvePins(plugin, pins, actual)...))
+	}
 
-	// Get actual installed versions
-	actual, err := getInstalledVersions(plugin)
-	if err != nil {
-		return err
+	// system and path: versions are verified, not installed
+	desired, unmanaged := splitUnmanaged(desired)
+	verified := make(map[string]bool)
+	for _, v := range unmanaged {
+		verified[v] = verifyUnmanaged(r.manager, plugin, v)
 	}
 
 	// Reconcile differences
 	missing, extra := reconcileVersions(desired, actual)
+	r.reportEOL(plugin, uniqueVersions(append(desired, actual...)))
+	drifts := classifyDrift(specs, missing, extra)
+	reportDrift(plugin, drifts)
 
 	// Show already installed versions (excluding extraneous versions)
 	for _, version := range actual {
@@ -47,152 +61,183 @@ func reconcileVersionsForPlugin(plugin string, specs []string) error {
 		}
 	}
 
-	if err := performVersionActions(plugin, missing, extra); err != nil {
+	if err := performVersionActions(r.manager, plugin, missing, opts); err != nil {
 		return err
 	}
 
-	// Set the last desired version as --home (used to be called global)
-	if len(desired) > 0 {
-		globalVersion := desired[len(desired)-1]
-		if err := exec.Command("asdf", "set", "--home", plugin, globalVersion).Run(); err != nil {
-			return fmt.Errorf("failed to set home %s version to %s: %w", plugin, globalVersion, err)
+	// Set the last configured version as --home (used to be called global),
+	// unless it could not be installed: the home version must be installed.
+	// Workspace pins never become the home version.
+	if len(configured) > 0 && (len(missing) == 0 || opts.noInstallReason() == "") {
+		home := configured[len(configured)-1]
+		if isUnmanaged(home) && !verified[home] {
+			fmt.Printf("✗ - %s: not setting %s as the home version\n", plugin, home)
+		} else if err := setHomeVersion(r.manager, plugin, home, opts); err != nil {
+			return err
 		}
+	}
+
+	// Remove extraneous versions last, once the home version has moved off them
+	return r.removeExtraneousVersions(plugin, specs, desired, extra, superseded(drifts), opts)
+}
 
-		// Verify --home version was set
-		out, err := exec.Command("asdf", "current", "--no-header", plugin).Output()
+// setHomeVersion sets and verifies the --home version of a plugin (used to be called global).
+// In plan mode, it only shows the command.
+func setHomeVersion(m RuntimeManager, plugin, globalVersion string, opts Options) error {
+	if opts.DryRun {
+		fmt.Printf("- To set %s %s as the home version:\n", plugin, globalVersion)
+		fmt.Printf(" %s\n", CommandLine(m, SetDefault, plugin, globalVersion))
+		return nil
+	}
+	if err := Run(m, SetDefault, plugin, globalVersion); err != nil {
+		return fmt.Errorf("failed to set home %s version to %s: %w", plugin, globalVersion, err)
+	}
+
+	// Verify --home version was set
+	current, err := m.Default(plugin)
+	if err != nil {
+		return err
+	}
+	if current == globalVersion {
+		fmt.Printf("✓ - %s %s is set as the home version\n", plugin, globalVersion)
+	} else {
+		fmt.Printf("✗ - Failed to set %s %s as the home version\n", plugin, globalVersion)
+	}
+	return nil
+}
+
+// resolveSpecs resolves every spec for a plugin, returning the sorted unique versions
+func (r *resolver) resolveSpecs(plugin string, specs []string, installed []string) ([]string, error) {
+	var resolvedVersions []string
+	for _, spec := range specs {
+		resolved, err := r.resolveVersion(plugin, spec, installed)
 		if err != nil {
-			return fmt.Errorf("failed to get current %s version: %w", plugin, err)
-		}
-		current := strings.Fields(string(out))[1] // [plugin] [version] [source]
-		if current == globalVersion {
-			fmt.Printf("✓ - %s %s is set as the home version\n", plugin, globalVersion)
-		} else {
-			fmt.Printf("✗ - Failed to set %s %s as the home version\n", plugin, globalVersion)
+			return nil, fmt.Errorf("resolving %s version %q: %w", plugin, spec, err)
 		}
+		fmt.Printf("✓ - %s: %s -> %s\n", plugin, spec, resolved)
+		resolvedVersions = append(resolvedVersions, resolved)
 	}
 
-	return nil
+	// Remove duplicates and sort
+	return uniqueVersions(resolvedVersions), nil
+}
+
+// resolvePins resolves the versions pinned by workspace projects.
+// A pin that cannot be resolved (e.g. a version asdf does not know)
+// is reported and skipped: one project should not break the whole run.
+func (r *resolver) resolvePins(plugin string, pins []string, installed []string) []string {
+	var resolved []string
+	for _, pin := range pins {
+		v, err := r.resolveVersion(plugin, pin, installed)
+		if err != nil {
+			fmt.Printf("△ - %s: skipping workspace pin %q: %v\n", plugin, pin, err)
+			continue
+		}
+		fmt.Printf("✓ - %s: %s -> %s (workspace)\n", plugin, pin, v)
+		resolved = append(resolved, v)
+	}
+	return resolved
 }
 
 // resolveVersion converts a version spec into a concrete version number.
 // Supported formats:
 // - "latest": resolves to the latest stable version (using asdf latest <plugin>)
-// - "lts": for nodejs only, resolves to the latest LTS version from nodejs.org
+// - "lts", "lts/<codename>": for nodejs only, resolves to the latest LTS version from nodejs.org
 // - "X[.Y[.Z]]": resolves to the latest version matching the prefix:
 //   - "3" -> latest 3.x.x
 //   - "3.12" -> latest 3.12.x
 //   - "3.12.0" -> exact version
-func resolveVersion(plugin, spec string) (string, error) {
+//
+// - "system", "path:<dir>", "ref:<git-ref>": used as is (see isLocalSpec)
+//
+// installed versions are candidates too, which matters offline.
+func (r *resolver) resolveVersion(plugin, spec string, installed []string) (string, error) {
 	switch {
+	case isLocalSpec(spec):
+		return resolveLocal(spec)
 	//  BECAUSE: asdf list all nodejs: IS BROKEN, we will handle everything
 	case plugin == "nodejs":
-		return resolveNodeVersion(spec)
+		return r.resolveNodeVersion(spec)
 	case spec == "latest":
-		return resolveLatest(plugin)
+		return r.resolveLatest(plugin, installed)
 	case isVersionPrefix(spec):
-		return resolveLatestPatch(plugin, spec)
+		return r.resolveLatestPatch(plugin, spec, installed)
 	default:
 		return "", fmt.Errorf("unsupported version spec %q for plugin %q", spec, plugin)
 	}
 }
 
 // resolveLatest returns the latest stable version for a plugin
-// by running asdf latest <plugin>; when not horribley broken
-func resolveLatest(plugin string) (string, error) {
-	cmd := exec.Command("asdf", "latest", plugin)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	out, err := cmd.Output() // Only captures stdout
+// by running asdf latest <plugin> (mise latest <tool>); when not horribley broken.
+// Offline, or when it fails (rate limiting), it falls back
+// to the highest stable version of the cached catalog.
+func (r *resolver) resolveLatest(plugin string, installed []string) (string, error) {
+	if r.offline {
+		return r.resolveLatestPatch(plugin, "", installed)
+	}
 
+	latest, err := r.manager.Latest(plugin)
 	if err != nil {
-		return "", fmt.Errorf("failed to get latest %s version: %w\nstderr: %s\nNote: Might be due to GitHub API rate limiting (60 requests/hour)\nCommand:\nasdf latest %s", plugin, err, stderr.String(), plugin)
+		if _, cacheErr := r.store.Read(r.catalog.key(plugin)); cacheErr == nil {
+			fmt.Printf("△ - %s latest %s failed, using the cached catalog: %v\n", r.manager.Name(), plugin, err)
+			return r.resolveLatestPatch(plugin, "", installed)
+		}
+		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return latest, nil
 }
 
 // resolveNodeVersion returns the appropriate Node.js version based on the spec:
 // - "lts": returns the latest LTS version
+// - "lts/<codename>": returns the latest version of that LTS line (e.g. "lts/jod")
 // - "latest": returns the latest version
 // - "X[.Y[.Z]]": returns the latest version matching the prefix
-func resolveNodeVersion(spec string) (string, error) {
-	out, err := exec.Command("curl", "-s", "https://nodejs.org/dist/index.json").Output()
+//
+// The release index is fetched from nodejs.org and cached (see nodejs.Client),
+// so resolution still works offline once the index has been fetched.
+func (r *resolver) resolveNodeVersion(spec string) (string, error) {
+	release, err := r.node.Resolve(spec)
+	reportNodeWarnings(r.node.Warnings())
 	if err != nil {
-		return "", fmt.Errorf("failed to get Node.js versions: %w", err)
-	}
-
-	var releases []struct {
-		Version string      `json:"version"`
-		LTS     interface{} `json:"lts"`
-	}
-	if err := json.Unmarshal(out, &releases); err != nil {
-		return "", fmt.Errorf("failed to parse Node.js versions: %w", err)
-	}
-
-	// three cases:
-	// 1. lts
-	// 2. latest
-	// 3. version prefix
-	// You can safely assume the versions are list in descending order
-	for _, r := range releases {
-		version := strings.TrimPrefix(r.Version, "v")
-
-		switch {
-		case spec == "latest":
-			return version, nil // First version is latest
-		case spec == "lts":
-			// when this version is an active LTS version,
-			// the LTS field is a release codename like "jod" otherwise it is false
-			if r.LTS != false {
-				return version, nil
-			}
-		case isVersionPrefix(spec):
-			if strings.HasPrefix(version, spec) {
-				return version, nil
-			}
-		default:
-			return "", fmt.Errorf("unsupported Node.js version spec: %s", spec)
-		}
+		return "", err
 	}
+	return release.Version, nil
+}
 
-	if spec == "lts" {
-		return "", fmt.Errorf("no LTS version found")
+// reportNodeWarnings shows the problems the Node.js client worked around
+func reportNodeWarnings(warnings []string) {
+	for _, w := range warnings {
+		fmt.Printf("△ - %s\n", w)
 	}
-	if isVersionPrefix(spec) {
-		return "", fmt.Errorf("no version found matching prefix %s", spec)
-	}
-	return "", fmt.Errorf("no matching version found for spec %s", spec)
 }
 
 // resolveLatestPatch finds the latest version matching a prefix (like "3.12" for python).
 // The process is:
-// 1. Get all available versions from asdf list all
-// 2. Filter versions that match the prefix exactly followed by a patch number
-// 3. Sort the matches using version sort (-V)
+// 1. Get all available versions from the catalog (asdf list all, cached) and installed versions
+// 2. Filter stable versions whose release segments start with the prefix
+// 3. Sort the matches using version.Compare
 // 4. Return the last (highest) version
 //
 // Examples for prefix "3.12":
 // - Input versions: ["3.12-dev", "3.12.0", "3.12.1", "3.12.0-rc1", "3.13.0", "3.2.0"]
-// - Regex "^3.12\.[0-9]+$" matches: ["3.12.0", "3.12.1"]
+// - Stable matches: ["3.12.0", "3.12.1"]
 // - After sorting: returns "3.12.1"
 //
 // Assumptions:
-// - We filter for clean versions (X.Y.Z where X,Y,Z are numbers)
-// - We need proper version sorting for correct results
-// - The prefix is already validated by isVersionPrefix
-func resolveLatestPatch(plugin, prefix string) (string, error) {
-	fmt.Printf("Resolving latest patch for %s: %s\n", plugin, prefix)
-	cmd := exec.Command("asdf", "list", "all", plugin)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	out, err := cmd.Output()
+// - We filter out pre-releases and variants (see filterVersions)
+// - The prefix is already validated by isVersionPrefix, or empty to match all versions
+func (r *resolver) resolveLatestPatch(plugin, prefix string, installed []string) (string, error) {
+	if prefix != "" {
+		fmt.Printf("Resolving latest patch for %s: %s\n", plugin, prefix)
+	}
+	versions, err := r.available(plugin, installed)
 	if err != nil {
-		return "", fmt.Errorf("failed to list %s versions: %w\nstderr: %s\nCommand:\nasdf list all %s", plugin, err, stderr.String(), plugin)
+		return "", err
 	}
 
-	versions := strings.Fields(string(out))
 	matches := filterAndSortVersions(versions, prefix)
 	if len(matches) == 0 {
-		return "", fmt.Errorf("no versions found matching %s for %s\nCommand:\nasdf list all %s", prefix, plugin, plugin)
+		return "", fmt.Errorf("no versions found matching %q for %s", prefix, plugin)
 	}
 
 	return matches[len(matches)-1], nil
@@ -250,66 +295,48 @@ func reconcileVersions(desired, actual []string) (missing, extra []string) {
 	return missing, extra
 }
 
-// performVersionActions installs missing versions and shows removal instructions for extra versions.
+// performVersionActions installs missing versions.
 // For each missing version:
-// - Runs asdf install <plugin> <version>
+// - Runs asdf install <plugin> <version> (offline or plan: only shows the command)
 // - Shows progress and completion messages
-// For each extra version:
-// - Shows the command to remove it: asdf uninstall <plugin> <version>
-func performVersionActions(plugin string, missing, extra []string) error {
+//
+// Extraneous versions are handled by removeExtraneousVersions.
+func performVersionActions(m RuntimeManager, plugin string, missing []string, opts Options) error {
 	// Install missing versions
 	for _, version := range missing {
+		if reason := opts.noInstallReason(); reason != "" {
+			fmt.Printf("✗ - %s version %s is missing (%s, not installing)\n", plugin, version, reason)
+			fmt.Printf(" %s\n", CommandLine(m, Install, plugin, version))
+			continue
+		}
 		fmt.Printf("✗ - %s version %s is missing. Installing...\n", plugin, version)
-		if err := exec.Command("asdf", "install", plugin, version).Run(); err != nil {
+		if err := Run(m, Install, plugin, version); err != nil {
 			return fmt.Errorf("failed to install %s version %s: %w", plugin, version, err)
 		}
 		fmt.Printf("  ✓ - %s version %s was successfully installed\n", plugin, version)
 	}
 
-	// Show extra versions
-	if len(extra) > 0 {
-		fmt.Printf("✗ - Extraneous %s versions found:\n", plugin)
-		for _, version := range extra {
-			fmt.Printf("- To remove %s version %s:\n", plugin, version)
-			fmt.Printf(" asdf uninstall %s %s\n", plugin, version)
-		}
-	}
-
 	return nil
 }
 
-// getInstalledVersions returns a list of currently installed versions for a plugin
-// by running asdf list <plugin> and cleaning up the output:
-// - Removes '*' prefix which marks the default/global version
-// - Example input:  "  21.7.3\n  22.12.0\n *22.12.0"
-// - Example output: ["21.7.3", "22.12.0", "22.12.0"]
-func getInstalledVersions(plugin string) ([]string, error) {
-	// BECAUSE: asdf list <plugin> now writes "No compatible versions installed" to stderr
-	// when no versions are installed, we need to capture stderr to handle this case
-	cmd := exec.Command("asdf", "list", plugin)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	out, err := cmd.Output() // Only captures stdout
-
-	if err != nil {
-		// If stderr contains "No compatible versions installed", return empty slice
-		if strings.Contains(stderr.String(), "No compatible versions installed") {
-			return []string{}, nil
+// reportEOL warns about the resolved and installed versions of a plugin
+// that are past, or nearing, their end-of-life (see eol.Dataset)
+func (r *resolver) reportEOL(plugin string, versions []string) {
+	product := eol.Product(plugin)
+	if product == "" {
+		return
+	}
+	if r.eol == nil {
+		d, err := eol.Load(r.store)
+		if err != nil {
+			fmt.Printf("△ - skipping end-of-life checks: %v\n", err)
+			return
 		}
-		return nil, fmt.Errorf("failed to list %s versions: %w\nstderr: %s", plugin, err, stderr.String())
+		r.eol = d
 	}
-
-	// Clean up version strings:
-	// - Remove '*' prefix which marks the default/global version
-	// - Example input:  "  21.7.3\n  22.12.0\n *22.12.0"
-	// - Example output: ["21.7.3", "22.12.0", "22.12.0"]
-	var versions []string
-	for _, v := range strings.Fields(string(out)) {
-		v = strings.TrimPrefix(v, "*")
-		v = strings.TrimSpace(v)
-		if v != "" {
-			versions = append(versions, v)
+	for _, v := range versions {
+		if warning := r.eol.Warning(product, v, time.Now()); warning != "" {
+			fmt.Printf("△ - %s (end-of-life data: %s)\n", warning, r.eol.Source)
 		}
 	}
-	return versions, nil
 }
@@ -0,0 +1,215 @@
+package asdf
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// skipDirs are never descended into when scanning for version files
+var skipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+// versionFiles maps the file names we scan for to their parser.
+// Each parser returns plugin -> pinned version specs.
+var versionFiles = map[string]func(lines []string) map[string][]string{
+	".tool-versions":  parseToolVersions,
+	".nvmrc":          parseNvmrc,
+	".python-version": parsePythonVersion,
+}
+
+// references maps plugin -> version -> files pinning that version
+type references map[string]map[string][]string
+
+// scanWorkspace walks the given directories ("~" is expanded) and collects the
+// versions pinned by every .tool-versions, .nvmrc and .python-version file found.
+// Hidden directories and skipDirs are not descended into; missing roots are ignored.
+func scanWorkspace(roots []string) (references, error) {
+	refs := make(references)
+	for _, root := range roots {
+		root, err := expandHome(root)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root && os.IsNotExist(err) {
+					return fs.SkipAll
+				}
+				return nil // unreadable entries are not worth failing for
+			}
+			if d.IsDir() {
+				if path != root && (strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()]) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if parse, ok := versionFiles[d.Name()]; ok {
+				return refs.addVersionFile(path, parse)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s for version files: %w", root, err)
+		}
+	}
+	return refs, nil
+}
+
+// addVersionFile reads a version file, ignoring "#" comments and blank lines
+func (refs references) addVersionFile(path string, parse func([]string) map[string][]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for plugin, versions := range parse(lines) {
+		for _, v := range versions {
+			refs.add(plugin, v, path)
+		}
+	}
+	return nil
+}
+
+// parseToolVersions parses "<plugin> <version> [<version>...]" lines
+func parseToolVersions(lines []string) map[string][]string {
+	pins := make(map[string][]string)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			pins[fields[0]] = append(pins[fields[0]], fields[1:]...)
+		}
+	}
+	return pins
+}
+
+// parseNvmrc parses an nvm version, mapping nvm aliases to our specs:
+// "v20.11.1" -> "20.11.1", "lts/*" -> "lts", "node" -> "latest", "lts/iron" as is
+func parseNvmrc(lines []string) map[string][]string {
+	if len(lines) == 0 {
+		return nil
+	}
+	v := strings.ToLower(strings.TrimPrefix(lines[0], "v"))
+	switch v {
+	case "lts/*":
+		v = "lts"
+	case "node", "stable":
+		v = "latest"
+	}
+	return map[string][]string{"nodejs": {v}}
+}
+
+// parsePythonVersion parses a pyenv .python-version: one version per line
+func parsePythonVersion(lines []string) map[string][]string {
+	if len(lines) == 0 {
+		return nil
+	}
+	return map[string][]string{"python": lines}
+}
+
+func (refs references) add(plugin, version, path string) {
+	if refs[plugin] == nil {
+		refs[plugin] = make(map[string][]string)
+	}
+	refs[plugin][version] = append(refs[plugin][version], path)
+}
+
+// references returns the versions pinned by version files under the roots,
+// scanning each root at most once per run
+func (r *resolver) references(roots []string) (references, error) {
+	merged := make(references)
+	for _, root := range roots {
+		refs, ok := r.scanned[root]
+		if !ok {
+			var err error
+			if refs, err = scanWorkspace([]string{root}); err != nil {
+				return nil, err
+			}
+			r.scanned[root] = refs
+		}
+		for plugin, versions := range refs {
+			for v, paths := range versions {
+				for _, p := range paths {
+					merged.add(plugin, v, p)
+				}
+			}
+		}
+	}
+	return merged, nil
+}
+
+// pluginsSorted returns the plugins in refs, sorted
+func (refs references) pluginsSorted() []string {
+	var plugins []string
+	for plugin := range refs {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	return plugins
+}
+
+// pins returns the version specs pinned for a plugin, sorted
+func (refs references) pins(plugin string) []string {
+	var versions []string
+	for v := range refs[plugin] {
+		versions = append(versions, v)
+	}
+	return sortVersions(versions)
+}
+
+// reportWorkspace shows which project pins which version.
+// Pins for plugins that are not configured are reported, but not installed.
+func reportWorkspace(roots []string, refs references, desiredVersions map[string][]string) {
+	fmt.Printf("\nWorkspace pins (%s):\n", strings.Join(roots, ", "))
+	if len(refs) == 0 {
+		fmt.Printf("✓ - No pinned versions found\n")
+		return
+	}
+	for _, plugin := range refs.pluginsSorted() {
+		_, managed := desiredVersions[plugin]
+		for _, v := range refs.pins(plugin) {
+			var projects []string
+			for _, path := range refs[plugin][v] {
+				projects = append(projects, fmt.Sprintf("%s (%s)", filepath.Dir(path), filepath.Base(path)))
+			}
+			if managed {
+				fmt.Printf("✓ - %s %s <- %s\n", plugin, v, strings.Join(projects, ", "))
+			} else {
+				fmt.Printf("△ - %s %s <- %s (plugin not configured, not installed)\n", plugin, v, strings.Join(projects, ", "))
+			}
+		}
+	}
+}
+
+// expandHome expands a leading "~/" to the user's home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,52 @@
+package asdf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanWorkspace(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"api/.tool-versions":                    "python 3.12.7\nnodejs 22.12.0 # pinned\n",
+		"web/.tool-versions":                    "nodejs 20.18.1 22.12.0\n",
+		"web/node_modules/x/.tool-versions":     "nodejs 8.0.0\n",
+		".hidden/.tool-versions":                "python 2.7.18\n",
+		"web/.nvmrc":                            "lts/*\n",
+		"cli/.nvmrc":                            "v20.11.1\n",
+		"ml/.python-version":                    "3.11\n3.10.14\n",
+		"api/.tool-versions.bak/.tool-versions": "python 3.6.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refs, err := scanWorkspace([]string{root, filepath.Join(root, "missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := references{
+		"python": {
+			"3.12.7":  {filepath.Join(root, "api/.tool-versions")},
+			"3.11":    {filepath.Join(root, "ml/.python-version")},
+			"3.10.14": {filepath.Join(root, "ml/.python-version")},
+		},
+		"nodejs": {
+			"22.12.0": {filepath.Join(root, "api/.tool-versions"), filepath.Join(root, "web/.tool-versions")},
+			"20.18.1": {filepath.Join(root, "web/.tool-versions")},
+			"20.11.1": {filepath.Join(root, "cli/.nvmrc")},
+			"lts":     {filepath.Join(root, "web/.nvmrc")},
+		},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("scanWorkspace() = %v, want %v", refs, want)
+	}
+}
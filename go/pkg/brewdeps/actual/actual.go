@@ -1,188 +1,228 @@
 package actual
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"maps"
 	"os/exec"
+	"slices"
 	"strings"
 
 	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+	"github.com/daneroo/dotfiles/go/pkg/collect"
 	"github.com/daneroo/dotfiles/go/pkg/config"
 )
 
-// State represents the current state of the system
-// type State struct {
-// 	Installed []types.Package
-// 	Deps      map[types.Package][]types.Package
-// }
-
-// GetActual returns the current state of installed packages and their dependencies
+// GetActual returns the current state of installed packages and their dependencies,
+// from brew info --json=v2 --installed (see collectInfo)
 func GetActual() (types.ActualState, error) {
-	depsMap := GetDepsMap(config.Global.Verbose)
-	installed := GetInstalled(config.Global.Verbose)
+	state, err := collectInfo()
+	if err != nil {
+		return types.ActualState{}, err
+	}
 
-	if err := Validate(installed, depsMap); err != nil {
+	fmt.Printf("✓ - Got Dependency Map\n")
+	if config.Global.Verbose {
+		fmt.Printf("Deps: (brew info --json=v2 --installed)\n %v\n\n", state.DepsMap)
+	}
+	fmt.Printf("✓ - Got Installed\n")
+	if config.Global.Verbose {
+		fmt.Printf("Installed: (brew info --json=v2 --installed)\n %v\n\n", state.Packages)
+	}
+
+	broken, err := Validate(state.Packages, state.DepsMap)
+	if err != nil {
 		return types.ActualState{}, err
 	}
+	broken.Report()
+	broken.Repair(state)
+	return state, nil
+}
 
-	return types.ActualState{
-		Packages: installed,
-		DepsMap:  depsMap,
-	}, nil
+// GetVersions returns the installed version of the given packages, keyed by the given names
+// (see types.ActualState.Versions)
+func GetVersions(pkgs []types.Package) (map[types.Package]string, error) {
+	state, err := collectInfo()
+	if err != nil {
+		return nil, err
+	}
+	return state.Versions(pkgs), nil
 }
 
-// GetInstalled returns a list of all installed packages (both formulae and casks) by running:
-//   - brew ls --full-name --formula
-//   - brew ls --full-name --cask
-//
-// The returned slice is the typed union of both commands, with each package marked
-// as either formula or cask. We use --full-name to get tap-qualified names where
-// appropriate (unlike dependencies which are always simple names).
-//
-// This list represents the current state of the system and will be compared against:
-//   - Required packages from brewDeps.yaml
-//   - Dependencies from brew deps --installed (--formula|--cask)
-func GetInstalled(verbose bool) []types.Package {
-	var pkgs []types.Package
-
-	configs := []struct {
-		arg    string
-		isCask bool
-	}{
-		{"--formula", false},
-		{"--cask", true},
-	}
-
-	for _, cfg := range configs {
-		out, err := exec.Command("brew", "ls", "--full-name", cfg.arg).Output()
-		if err != nil {
-			log.Fatal(err)
-		}
-		for _, name := range splitByLineNoEmpty(string(out)) {
-			pkgs = append(pkgs, types.Package{Name: name, IsCask: cfg.isCask})
-		}
+// kinds are the arguments brew info is run with, once per kind of package
+var kinds = []string{"--formula", "--cask"}
+
+// collectInfo runs brew info --json=v2 --installed for formulae and casks concurrently
+// (see collect.Run), each step building the packages of its kind with their dependencies,
+// and merges them (see parseInfo)
+func collectInfo() (types.ActualState, error) {
+	states := make([]types.ActualState, len(kinds))
+	var steps []collect.Step
+	for i, kind := range kinds {
+		steps = append(steps, collect.Step{Name: "brew info " + kind, Run: func() error {
+			out, err := exec.Command("brew", "info", "--json=v2", "--installed", kind).Output()
+			if err != nil {
+				return fmt.Errorf("brew info --json=v2 --installed %s failed: %w", kind, err)
+			}
+			var response infoResponse
+			if err := json.Unmarshal(out, &response); err != nil {
+				return fmt.Errorf("failed to parse brew info output: %w", err)
+			}
+			states[i] = response.state()
+			return nil
+		}})
+	}
+	if err := collect.Run(collect.Workers, steps...); err != nil {
+		return types.ActualState{}, err
 	}
 
-	fmt.Printf("✓ - Got Installed\n")
-	if verbose {
-		fmt.Printf("Installed: (brew ls --full-name)\n %v\n\n", pkgs)
+	state := types.ActualState{
+		DepsMap: make(map[types.Package][]types.Package),
+		Info:    make(map[types.Package]types.PackageInfo),
 	}
-	return pkgs
+	for _, s := range states {
+		state.Packages = append(state.Packages, s.Packages...)
+		maps.Copy(state.DepsMap, s.DepsMap)
+		maps.Copy(state.Info, s.Info)
+	}
+	canonicalizeDeps(state)
+	return state, nil
 }
 
-// GetDepsMap returns a map of installed packages to their dependencies by running:
-//   - brew deps --installed --formula
-//   - brew deps --installed --cask
-//
-// Note: We don't use --full-name for deps because:
-//   - Dependencies are always formulae (not casks)
-//   - Simple names are sufficient for dependencies
-//   - Tap qualification is only needed for explicitly required packages
-//
-// The returned map includes both formulae and casks as keys, but all dependencies
-// (values) are formulae, following Homebrew's (ASSUMED) rules:
-//   - Formulae can depend on other formulae
-//   - Casks can depend on formulae
-//   - Neither can depend on casks
-func GetDepsMap(verbose bool) map[types.Package][]types.Package {
-	deps := make(map[types.Package][]types.Package)
-
-	configs := []struct {
-		arg    string
-		isCask bool
-	}{
-		{"--formula", false},
-		{"--cask", true},
-	}
-
-	for _, cfg := range configs {
-		out, err := exec.Command("brew", "deps", "--installed", cfg.arg).Output()
-		if err != nil {
-			log.Fatal(err)
+// Validate checks the assumptions the extraneous check relies on:
+//   - every installed package appears as a key in the deps map, i.e.
+//     `brew info --installed` returns dependency information for it:
+//     otherwise the dependencies of a package are unknown, an error
+//   - every dependency is installed, as the kind (formula or cask) the edge says:
+//     the broken dependencies are returned, to be reported and repaired (see BrokenDeps)
+func Validate(installed []types.Package, depsMap map[types.Package][]types.Package) (BrokenDeps, error) {
+	isInstalled := make(map[types.Package]bool)
+	for _, inst := range installed {
+		isInstalled[inst] = true
+	}
+
+	var broken BrokenDeps
+	e := &ValidationError{}
+	for _, inst := range installed {
+		deps, ok := depsMap[inst]
+		// force an inconsistency to test output and error handling
+		// if inst.Name == "git" || inst.Name == "vlc" {
+		// 	ok = false
+		// }
+		if !ok {
+			e.MissingFromDepsMap = append(e.MissingFromDepsMap, inst)
 		}
-		for _, line := range splitByLineNoEmpty(string(out)) {
-			ss := strings.SplitN(line, ":", 2)
-			if len(ss) != 2 {
-				log.Fatalf("Cannot split(:) %q \n", line)
+		for _, dep := range deps {
+			switch {
+			case isInstalled[dep]:
+			case isInstalled[otherKind(dep)]:
+				broken.KindMismatches = append(broken.KindMismatches, Edge{From: inst, To: dep})
+			default:
+				broken.NotInstalled = append(broken.NotInstalled, Edge{From: inst, To: dep})
 			}
-			pkg := types.Package{Name: ss[0], IsCask: cfg.isCask}
-			// Note: dependencies are always formulae
-			deps[pkg] = parseDepsAsPackages(ss[1])
 		}
 	}
-
-	fmt.Printf("✓ - Got Dependency Map\n")
-	if verbose {
-		fmt.Printf("Deps: (brew deps --installed)\n %v\n\n", deps)
+	if len(e.MissingFromDepsMap) > 0 {
+		return BrokenDeps{}, e
 	}
-	return deps
+	return broken, nil
 }
 
-// parseDepsAsPackages converts a space-separated string of package names into Package objects.
-// Note: All dependencies (the right-hand side of `brew deps` output) are assumed to be formulae,
-// not casks. This matches Homebrew's (ASSUMED) rules where:
-//   - Formulae can depend on other formulae
-//   - Casks can depend on formulae
-//   - Neither can depend on casks
-func parseDepsAsPackages(s string) []types.Package {
-	var deps []types.Package
-	for _, name := range strings.Fields(s) {
-		// All dependencies are formulae
-		deps = append(deps, types.Package{Name: name, IsCask: false})
-	}
-	return deps
+// otherKind returns the package of the same name, as the other kind: a cask for a formula
+func otherKind(pkg types.Package) types.Package {
+	return types.Package{Name: pkg.Name, IsCask: !pkg.IsCask}
 }
 
-func splitByLineNoEmpty(s string) []string {
-	return filter(
-		strings.Split(s, "\n"),
-		nonEmptyString,
-	)
+// BrokenDeps are dependencies that are not installed as the kind (formula or cask)
+// the edge says (see Validate)
+type BrokenDeps struct {
+	// KindMismatches are dependencies installed only as the other kind: the metadata
+	// breaks Homebrew's rules (formulae never depend on casks), but the package is installed
+	KindMismatches []Edge
+	// NotInstalled are dependencies that are not installed at all: a broken install (see brew missing)
+	NotInstalled []Edge
 }
 
-func nonEmptyString(s string) bool {
-	return len(s) > 0
-}
+// Repair fixes the broken dependencies in the deps map (and the runtime dependencies
+// of Info), so that the graph only holds installed packages:
+//   - a kind mismatch points to the package installed as the other kind,
+//     which its dependent still holds: it is not extraneous
+//   - a dependency that is not installed is dropped
+func (b BrokenDeps) Repair(state types.ActualState) {
+	retarget := make(map[Edge]bool)
+	for _, e := range b.KindMismatches {
+		retarget[e] = true
+	}
+	drop := make(map[Edge]bool)
+	for _, e := range b.NotInstalled {
+		drop[e] = true
+	}
+	if len(retarget)+len(drop) == 0 {
+		return
+	}
 
-func filter(vs []string, f func(string) bool) []string {
-	vsf := make([]string, 0)
-	for _, v := range vs {
-		if f(v) {
-			vsf = append(vsf, v)
+	for _, inst := range state.Packages {
+		deps := state.DepsMap[inst]
+		var repaired []types.Package
+		changed := false
+		for _, dep := range deps {
+			edge := Edge{From: inst, To: dep}
+			switch {
+			case drop[edge]:
+				changed = true
+				continue
+			case retarget[edge]:
+				dep, changed = otherKind(dep), true
+			}
+			if !slices.Contains(repaired, dep) {
+				repaired = append(repaired, dep)
+			}
+		}
+		if changed {
+			state.DepsMap[inst] = repaired
+			if info, ok := state.Info[inst]; ok {
+				info.RuntimeDeps = repaired
+				state.Info[inst] = info
+			}
 		}
 	}
-	return vsf
 }
 
-// Validate checks if all installed packages appear as keys in the deps map.
-// This verifies that `brew deps --installed` returns dependency information
-// for every installed package.
-//
-// Note: This is a precondition for the extraneous check, which assumes
-// we can look up dependencies for any installed package.
-func Validate(installed []types.Package, depsMap map[types.Package][]types.Package) error {
-	var missingFromMap []types.Package
-	for _, inst := range installed {
-		_, ok := depsMap[inst]
-		// force an inconsistency to test output and error handling
-		// if inst.Name == "git" || inst.Name == "vlc" {
-		// 	ok = false
-		// }
-		if !ok {
-			missingFromMap = append(missingFromMap, inst)
-		}
+// Report shows the broken dependencies, and how they are repaired
+func (b BrokenDeps) Report() {
+	for _, e := range b.KindMismatches {
+		fmt.Printf("✗ - %s: dependency installed as the other kind, using %s\n", e, describe(otherKind(e.To)))
+	}
+	for _, e := range b.NotInstalled {
+		fmt.Printf("✗ - %s: dependency not installed, ignored\n", e)
+	}
+	if len(b.NotInstalled) > 0 {
+		fmt.Printf(" Run: brew missing\n")
 	}
-	if len(missingFromMap) > 0 {
-		return &ValidationError{MissingFromDepsMap: missingFromMap}
+}
+
+// Edge is a dependency: From depends on To
+type Edge struct {
+	From, To types.Package
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("%s -> %s", describe(e.From), describe(e.To))
+}
+
+// describe shows the kind of a package: "vlc (cask)", "wget (formula)"
+func describe(pkg types.Package) string {
+	if pkg.IsCask {
+		return pkg.Name + " (cask)"
 	}
-	return nil
+	return pkg.Name + " (formula)"
 }
 
 // ValidationError represents an inconsistency between installed packages
-// and the dependency map. This indicates that some installed packages
-// are not present as keys in the deps map, which is a precondition
-// for the extraneous check.
+// and the dependency map (see Validate), which the extraneous check
+// cannot be trusted with.
 type ValidationError struct {
+	// MissingFromDepsMap are installed packages without dependency information
 	MissingFromDepsMap []types.Package
 }
 
@@ -191,6 +231,5 @@ func (e *ValidationError) Error() string {
 	for _, pkg := range e.MissingFromDepsMap {
 		msgs = append(msgs, fmt.Sprintf("%q (cask=%v)", pkg.Name, pkg.IsCask))
 	}
-	return fmt.Sprintf("dependency map inconsistency: installed packages not found in deps map: %s",
-		strings.Join(msgs, ", "))
+	return "dependency map inconsistency: installed packages not found in deps map: " + strings.Join(msgs, ", ")
 }
@@ -0,0 +1,217 @@
+package actual
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+// infoResponse is the output of brew info --json=v2 --installed (the fields we use)
+type infoResponse struct {
+	Formulae []formulaInfo `json:"formulae"`
+	Casks    []caskInfo    `json:"casks"`
+}
+
+type formulaInfo struct {
+	Name     string   `json:"name"`
+	FullName string   `json:"full_name"`
+	Tap      string   `json:"tap"`
+	Aliases  []string `json:"aliases"`
+	OldNames []string `json:"oldnames"`
+	// OldName is the single old name of brew versions before oldnames
+	OldName  string `json:"oldname"`
+	Versions struct {
+		Stable string `json:"stable"`
+	} `json:"versions"`
+	Revision          int      `json:"revision"`
+	Pinned            bool     `json:"pinned"`
+	Outdated          bool     `json:"outdated"`
+	Deprecated        bool     `json:"deprecated"`
+	Disabled          bool     `json:"disabled"`
+	Caveats           *string  `json:"caveats"`
+	Dependencies      []string `json:"dependencies"`
+	BuildDependencies []string `json:"build_dependencies"`
+	// Installed holds one receipt per installed version (oldest first)
+	Installed []struct {
+		Version             string `json:"version"`
+		InstalledOnRequest  bool   `json:"installed_on_request"`
+		RuntimeDependencies []struct {
+			FullName         string `json:"full_name"`
+			DeclaredDirectly bool   `json:"declared_directly"`
+		} `json:"runtime_dependencies"`
+	} `json:"installed"`
+}
+
+type caskInfo struct {
+	Token      string   `json:"token"`
+	FullToken  string   `json:"full_token"`
+	Tap        string   `json:"tap"`
+	OldTokens  []string `json:"old_tokens"`
+	Version    string   `json:"version"`
+	Installed  *string  `json:"installed"`
+	Outdated   bool     `json:"outdated"`
+	Deprecated bool     `json:"deprecated"`
+	Disabled   bool     `json:"disabled"`
+	Caveats    *string  `json:"caveats"`
+	DependsOn  struct {
+		Formula []string `json:"formula"`
+		Cask    []string `json:"cask"`
+	} `json:"depends_on"`
+}
+
+// parseInfo builds the actual state from brew info --json=v2 --installed.
+//
+// Packages are named like brew ls --full-name: tap-qualified outside the core taps
+// (e.g. "teamookla/speedtest/speedtest"), and so are their dependencies.
+//
+// The dependencies of a formula are the direct runtime dependencies recorded
+// when it was installed (its receipt), not those of the current formula, which may
+// have changed since. Receipts written by older brew versions do not flag direct
+// dependencies: all (transitive) runtime dependencies are used then, which is
+// equivalent for reachability. Without any receipt data, the declared dependencies are used.
+//
+// Dependency edges are typed by kind, from the metadata:
+//   - Formulae depend on formulae (their runtime dependencies)
+//   - Casks depend on formulae (depends_on.formula) and on casks (depends_on.cask)
+//
+// Dependencies named otherwise than by the full name of an installed package (an old name
+// recorded before a rename, an alias) are resolved to it, see types.ActualState.Canonical.
+// Validate reports edges that do not match what is installed.
+func parseInfo(data []byte) (types.ActualState, error) {
+	var response infoResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return types.ActualState{}, fmt.Errorf("failed to parse brew info output: %w", err)
+	}
+	state := response.state()
+	canonicalizeDeps(state)
+	return state, nil
+}
+
+// state builds the packages of a response with their dependencies, as named
+// by the metadata: they are only canonical once every package is known (see canonicalizeDeps)
+func (response infoResponse) state() types.ActualState {
+	state := types.ActualState{
+		DepsMap: make(map[types.Package][]types.Package),
+		Info:    make(map[types.Package]types.PackageInfo),
+	}
+	for _, f := range response.Formulae {
+		if len(f.Installed) == 0 {
+			continue
+		}
+		receipt := f.Installed[len(f.Installed)-1] // the most recent
+		pkg := types.Package{Name: f.FullName}
+
+		var direct, all []string
+		for _, dep := range receipt.RuntimeDependencies {
+			all = append(all, dep.FullName)
+			if dep.DeclaredDirectly {
+				direct = append(direct, dep.FullName)
+			}
+		}
+		switch {
+		case len(direct) > 0:
+		case len(all) > 0:
+			direct = all
+		default:
+			direct = f.Dependencies
+		}
+
+		info := types.PackageInfo{
+			Version:        receipt.Version,
+			CurrentVersion: formulaVersion(f.Versions.Stable, f.Revision),
+			Tap:            f.Tap,
+			Aliases:        f.Aliases,
+			OldNames:       oldNames(f.OldNames, f.OldName),
+			OnRequest:      receipt.InstalledOnRequest,
+			Pinned:         f.Pinned,
+			Outdated:       f.Outdated,
+			Deprecated:     f.Deprecated,
+			Disabled:       f.Disabled,
+			RuntimeDeps:    formulae(direct),
+			BuildDeps:      formulae(f.BuildDependencies),
+			Caveats:        deref(f.Caveats),
+		}
+		state.Packages = append(state.Packages, pkg)
+		state.DepsMap[pkg] = info.RuntimeDeps
+		state.Info[pkg] = info
+	}
+	for _, c := range response.Casks {
+		if c.Installed == nil {
+			continue
+		}
+		pkg := types.Package{Name: c.FullToken, IsCask: true}
+		info := types.PackageInfo{
+			Version:        *c.Installed,
+			CurrentVersion: c.Version,
+			Tap:            c.Tap,
+			OldNames:       c.OldTokens,
+			OnRequest:      true, // casks are never installed as a dependency
+			Outdated:       c.Outdated,
+			Deprecated:     c.Deprecated,
+			Disabled:       c.Disabled,
+			RuntimeDeps:    append(formulae(c.DependsOn.Formula), casks(c.DependsOn.Cask)...),
+			Caveats:        deref(c.Caveats),
+		}
+		state.Packages = append(state.Packages, pkg)
+		state.DepsMap[pkg] = info.RuntimeDeps
+		state.Info[pkg] = info
+	}
+	return state
+}
+
+// canonicalizeDeps names every installed dependency by its full name
+// (DepsMap shares the slices of Info)
+func canonicalizeDeps(state types.ActualState) {
+	for _, pkg := range state.Packages {
+		info := state.Info[pkg]
+		for i, dep := range info.RuntimeDeps {
+			if canonical, match := state.Canonical(dep); match != types.NotInstalled {
+				info.RuntimeDeps[i] = canonical
+			}
+		}
+	}
+}
+
+// oldNames merges the old names of a formula, in either format
+func oldNames(names []string, name string) []string {
+	if name != "" && !slices.Contains(names, name) {
+		names = append(names, name)
+	}
+	return names
+}
+
+// formulaVersion returns the version brew installs: the stable version,
+// with its revision suffix if any ("1.2.3_1")
+func formulaVersion(stable string, revision int) string {
+	if revision > 0 {
+		return fmt.Sprintf("%s_%d", stable, revision)
+	}
+	return stable
+}
+
+// formulae converts formula names into packages
+func formulae(names []string) []types.Package {
+	var pkgs []types.Package
+	for _, name := range names {
+		pkgs = append(pkgs, types.Package{Name: name, IsCask: false})
+	}
+	return pkgs
+}
+
+// casks converts cask tokens into packages
+func casks(tokens []string) []types.Package {
+	var pkgs []types.Package
+	for _, token := range tokens {
+		pkgs = append(pkgs, types.Package{Name: token, IsCask: true})
+	}
+	return pkgs
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
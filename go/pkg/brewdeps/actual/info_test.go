@@ -0,0 +1,194 @@
+package actual
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+const infoJSON = `{
+  "formulae": [
+    {
+      "name": "wget", "full_name": "wget", "tap": "homebrew/core",
+      "versions": {"stable": "1.25.0"}, "revision": 0,
+      "pinned": true, "outdated": true, "deprecated": false, "disabled": false,
+      "caveats": null,
+      "dependencies": ["libidn2", "openssl@3"],
+      "build_dependencies": ["pkgconf"],
+      "installed": [{
+        "version": "1.24.5", "installed_on_request": true,
+        "runtime_dependencies": [
+          {"full_name": "ca-certificates", "declared_directly": false},
+          {"full_name": "openssl@3", "declared_directly": true}
+        ]
+      }]
+    },
+    {
+      "name": "openssl@3", "full_name": "openssl@3", "tap": "homebrew/core",
+      "versions": {"stable": "3.4.0"}, "revision": 1,
+      "installed": [{
+        "version": "3.4.0_1", "installed_on_request": false,
+        "runtime_dependencies": [{"full_name": "ca-certificates"}]
+      }]
+    },
+    {
+      "name": "speedtest", "full_name": "teamookla/speedtest/speedtest", "tap": "teamookla/speedtest",
+      "versions": {"stable": "1.2.0"}, "deprecated": true,
+      "dependencies": [],
+      "installed": [{"version": "1.2.0", "installed_on_request": true}]
+    },
+    {
+      "name": "ca-certificates", "full_name": "ca-certificates", "tap": "homebrew/core",
+      "versions": {"stable": "2024.11.26"},
+      "installed": [{"version": "2024.11.26", "installed_on_request": false, "runtime_dependencies": []}]
+    },
+    {
+      "name": "notinstalled", "full_name": "notinstalled", "installed": []
+    }
+  ],
+  "casks": [
+    {
+      "token": "vlc", "full_token": "vlc", "tap": "homebrew/cask",
+      "version": "3.0.21", "installed": "3.0.20", "outdated": true,
+      "caveats": "Restart",
+      "depends_on": {"macos": {">=": ["10.13"]}}
+    },
+    {
+      "token": "fuse-t-sshfs", "full_token": "macos-fuse-t/cask/fuse-t-sshfs", "tap": "macos-fuse-t/cask",
+      "version": "1.0.2", "installed": "1.0.2",
+      "depends_on": {"cask": ["vlc"], "formula": ["wget"]}
+    }
+  ]
+}`
+
+func TestParseInfo(t *testing.T) {
+	state, err := parseInfo([]byte(infoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wget := types.Package{Name: "wget"}
+	openssl := types.Package{Name: "openssl@3"}
+	speedtest := types.Package{Name: "teamookla/speedtest/speedtest"}
+	caCertificates := types.Package{Name: "ca-certificates"}
+	vlc := types.Package{Name: "vlc", IsCask: true}
+	sshfs := types.Package{Name: "macos-fuse-t/cask/fuse-t-sshfs", IsCask: true}
+
+	wantPackages := []types.Package{wget, openssl, speedtest, caCertificates, vlc, sshfs}
+	if !reflect.DeepEqual(state.Packages, wantPackages) {
+		t.Errorf("Packages = %v, want %v", state.Packages, wantPackages)
+	}
+	wantDeps := map[types.Package][]types.Package{
+		wget:           {openssl},        // direct dependencies only
+		openssl:        {caCertificates}, // no direct flags: all runtime dependencies
+		speedtest:      nil,              // no receipt data: declared dependencies
+		caCertificates: nil,
+		vlc:            nil,
+		sshfs:          {wget, vlc}, // typed by kind: formulae, then casks
+	}
+	if !reflect.DeepEqual(state.DepsMap, wantDeps) {
+		t.Errorf("DepsMap = %v, want %v", state.DepsMap, wantDeps)
+	}
+	if broken, err := Validate(state.Packages, state.DepsMap); err != nil || !reflect.DeepEqual(broken, BrokenDeps{}) {
+		t.Errorf("Validate() = %+v, %v", broken, err)
+	}
+
+	info := state.Info[wget]
+	if info.Version != "1.24.5" || info.CurrentVersion != "1.25.0" || !info.Pinned || !info.Outdated || !info.OnRequest {
+		t.Errorf("Info[wget] = %+v", info)
+	}
+	if !reflect.DeepEqual(info.BuildDeps, []types.Package{{Name: "pkgconf"}}) {
+		t.Errorf("Info[wget].BuildDeps = %v", info.BuildDeps)
+	}
+	if info := state.Info[openssl]; info.OnRequest || info.CurrentVersion != "3.4.0_1" || info.Outdated {
+		t.Errorf("Info[openssl@3] = %+v", info)
+	}
+	if info := state.Info[speedtest]; !info.Deprecated || info.Tap != "teamookla/speedtest" {
+		t.Errorf("Info[speedtest] = %+v", info)
+	}
+	if info := state.Info[vlc]; info.Version != "3.0.20" || info.Caveats != "Restart" || !info.OnRequest {
+		t.Errorf("Info[vlc] = %+v", info)
+	}
+
+	outdated := Outdated(state)
+	if len(outdated.Formulae) != 1 || outdated.Formulae[0].Name != "wget" || len(outdated.Casks) != 1 {
+		t.Errorf("Outdated() = %+v", outdated)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	wget := types.Package{Name: "wget"}
+	vlc := types.Package{Name: "vlc", IsCask: true}
+	installed := []types.Package{wget, vlc}
+
+	_, err := Validate(installed, map[types.Package][]types.Package{
+		wget: {{Name: "openssl@3"}}, // not installed: repaired, see TestRepairBrokenDeps
+	})
+	var validErr *ValidationError
+	if !errors.As(err, &validErr) {
+		t.Fatalf("Validate() = %v, want a ValidationError", err)
+	}
+	want := &ValidationError{MissingFromDepsMap: []types.Package{vlc}}
+	if !reflect.DeepEqual(validErr, want) {
+		t.Errorf("Validate() = %+v, want %+v", validErr, want)
+	}
+}
+
+func TestRepairBrokenDeps(t *testing.T) {
+	// wget's receipt still lists libidn2, uninstalled since; fuse-t-sshfs
+	// depends on a vlc formula, but vlc is only installed as a cask
+	state, err := parseInfo([]byte(`{
+  "formulae": [{
+    "name": "wget", "full_name": "wget", "versions": {"stable": "1.25.0"},
+    "installed": [{
+      "version": "1.25.0", "installed_on_request": true,
+      "runtime_dependencies": [
+        {"full_name": "libidn2", "declared_directly": true},
+        {"full_name": "openssl@3", "declared_directly": true}
+      ]
+    }]
+  }, {
+    "name": "openssl@3", "full_name": "openssl@3", "versions": {"stable": "3.4.0"},
+    "installed": [{"version": "3.4.0", "runtime_dependencies": []}]
+  }],
+  "casks": [
+    {"token": "vlc", "full_token": "vlc", "version": "3.0.21", "installed": "3.0.21"},
+    {"token": "fuse-t-sshfs", "full_token": "fuse-t-sshfs", "version": "1.0.2", "installed": "1.0.2",
+     "depends_on": {"formula": ["vlc"]}}
+  ]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wget := types.Package{Name: "wget"}
+	vlc := types.Package{Name: "vlc", IsCask: true}
+	sshfs := types.Package{Name: "fuse-t-sshfs", IsCask: true}
+	broken, err := Validate(state.Packages, state.DepsMap)
+	if err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	want := BrokenDeps{
+		KindMismatches: []Edge{{From: sshfs, To: types.Package{Name: "vlc"}}},
+		NotInstalled:   []Edge{{From: wget, To: types.Package{Name: "libidn2"}}},
+	}
+	if !reflect.DeepEqual(broken, want) {
+		t.Errorf("Validate() = %+v, want %+v", broken, want)
+	}
+
+	broken.Repair(state)
+	if got, want := state.DepsMap[wget], []types.Package{{Name: "openssl@3"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DepsMap[wget] = %v, want %v", got, want)
+	}
+	if got := state.Info[wget].RuntimeDeps; !reflect.DeepEqual(got, state.DepsMap[wget]) {
+		t.Errorf("Info[wget].RuntimeDeps = %v, want %v", got, state.DepsMap[wget])
+	}
+	if got, want := state.DepsMap[sshfs], []types.Package{vlc}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DepsMap[fuse-t-sshfs] = %v, want %v", got, want)
+	}
+	if broken, _ := Validate(state.Packages, state.DepsMap); !reflect.DeepEqual(broken, BrokenDeps{}) {
+		t.Errorf("Validate() after Repair = %+v, want none", broken)
+	}
+}
@@ -1,60 +1,159 @@
 package actual
 
 import (
-	"encoding/json"
 	"fmt"
 	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+	"github.com/daneroo/dotfiles/go/pkg/version"
 )
 
-type outdatedFormula struct {
-	Name              string   `json:"name"`
-	InstalledVersions []string `json:"installed_versions"`
-	CurrentVersion    string   `json:"current_version"`
+// OutdatedPackage is an installed package with a newer version available
+type OutdatedPackage struct {
+	types.Package
+	Installed string
+	Current   string
+	Pinned    bool
+	// Bump is how far the upgrade goes (see Classify)
+	Bump Bump
 }
 
-type outdatedResponse struct {
-	Formulae []outdatedFormula `json:"formulae"`
-	Casks    []outdatedFormula `json:"casks"`
-}
+// Bump classifies an upgrade by the release segment it changes
+type Bump string
 
-// CheckOutdated returns true if any packages need updating
-// First runs brew update to ensure we have latest information
-func CheckOutdated() (bool, error) {
-	// Run brew update first
-	if err := exec.Command("brew", "update").Run(); err != nil {
-		return false, fmt.Errorf("brew update failed: %w", err)
-	}
+const (
+	// BumpUnknown: a version is missing or not numeric, e.g. casks versioned "latest"
+	BumpUnknown Bump = "unknown"
+	BumpPatch   Bump = "patch"
+	BumpMinor   Bump = "minor"
+	BumpMajor   Bump = "major"
+)
 
-	out, err := exec.Command("brew", "outdated", "--json").Output()
-	if err != nil {
-		return false, err
+// Classify tells how far an upgrade goes: major (1.x -> 2.x, or 0.1 -> 0.2 as
+// 0.x minors may break too), minor (1.1 -> 1.2), or patch (anything smaller,
+// e.g. 1.1.1 -> 1.1.2, or a new revision 1.1.1 -> 1.1.1_1).
+// Brew revisions ("_1") and cask build suffixes (",2024.10") are ignored; versions
+// that do not start with a number (empty, "latest", "stable-abc") are BumpUnknown.
+func Classify(installed, current string) Bump {
+	parse := func(s string) (version.Version, bool) {
+		s, _, _ = strings.Cut(s, ",")
+		s, _, _ = strings.Cut(s, "_")
+		v, err := version.Parse(s)
+		return v, err == nil
 	}
-
-	var response outdatedResponse
-	if err := json.Unmarshal(out, &response); err != nil {
-		return false, err
+	from, okFrom := parse(installed)
+	to, okTo := parse(current)
+	if !okFrom || !okTo {
+		return BumpUnknown
 	}
+	segment := func(v version.Version, i int) int {
+		if i < len(v.Release) {
+			return v.Release[i]
+		}
+		return 0
+	}
+	switch {
+	case segment(from, 0) != segment(to, 0):
+		return BumpMajor
+	case segment(from, 1) != segment(to, 1) && segment(from, 0) == 0:
+		return BumpMajor
+	case segment(from, 1) != segment(to, 1):
+		return BumpMinor
+	}
+	return BumpPatch
+}
 
-	hasUpdates := len(response.Formulae) > 0 || len(response.Casks) > 0
-	if !hasUpdates {
-		fmt.Printf("✓ - All formulae and casks are up to date\n")
-		return false, nil
+// Majors returns the major upgrades among outdated packages
+func Majors(pkgs []OutdatedPackage) []OutdatedPackage {
+	var majors []OutdatedPackage
+	for _, p := range pkgs {
+		if p.Bump == BumpMajor {
+			majors = append(majors, p)
+		}
 	}
+	return majors
+}
 
-	fmt.Printf("✗ - Updates available: (%d packages)\n",
-		len(response.Formulae)+len(response.Casks))
+// OutdatedPackages lists the outdated formulae and casks, sorted by name
+type OutdatedPackages struct {
+	Formulae []OutdatedPackage
+	Casks    []OutdatedPackage
+}
 
-	// Show individual updates
-	for _, f := range response.Formulae {
-		fmt.Printf(" - %s: %s -> %s\n",
-			f.Name, f.InstalledVersions[0], f.CurrentVersion)
+// Outdated returns the outdated packages of the actual state
+func Outdated(state types.ActualState) OutdatedPackages {
+	var o OutdatedPackages
+	for _, pkg := range state.Packages {
+		info := state.Info[pkg]
+		if !info.Outdated {
+			continue
+		}
+		p := OutdatedPackage{
+			Package:   pkg,
+			Installed: info.Version,
+			Current:   info.CurrentVersion,
+			Pinned:    info.Pinned,
+			Bump:      Classify(info.Version, info.CurrentVersion),
+		}
+		if pkg.IsCask {
+			o.Casks = append(o.Casks, p)
+		} else {
+			o.Formulae = append(o.Formulae, p)
+		}
 	}
-	for _, c := range response.Casks {
-		fmt.Printf(" - %s: %s -> %s\n",
-			c.Name, c.InstalledVersions[0], c.CurrentVersion)
+	for _, pkgs := range [][]OutdatedPackage{o.Formulae, o.Casks} {
+		sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
 	}
+	return o
+}
+
+// Upgrade upgrades outdated packages (plan: only shows the commands).
+// The actual state must be collected again afterwards.
+func Upgrade(pkgs []OutdatedPackage, dryRun bool) error {
+	for _, isCask := range []bool{false, true} {
+		args := []string{"upgrade", kindFlag(isCask)}
+		for _, p := range pkgs {
+			if p.IsCask == isCask {
+				args = append(args, p.Name)
+			}
+		}
+		if len(args) == 2 {
+			continue
+		}
+		if dryRun {
+			fmt.Printf(" brew %s\n", strings.Join(args, " "))
+			continue
+		}
+		if err := exec.Command("brew", args...).Run(); err != nil {
+			return fmt.Errorf("brew %s failed: %w", strings.Join(args, " "), err)
+		}
+		fmt.Printf("  ✓ - brew %s\n", strings.Join(args, " "))
+	}
+	return nil
+}
 
-	fmt.Printf("\nRun:\n")
-	fmt.Printf(" brew upgrade && brew cleanup\n")
-	return true, nil
+// Pin pins held formulae, so that brew upgrade leaves them at their installed version
+// (plan: only shows the commands)
+func Pin(pkgs []types.Package, dryRun bool) error {
+	for _, pkg := range pkgs {
+		if dryRun {
+			fmt.Printf("✗ - %s is held, but not pinned\n", pkg.Name)
+			fmt.Printf(" brew pin %s\n", pkg.Name)
+			continue
+		}
+		if err := exec.Command("brew", "pin", pkg.Name).Run(); err != nil {
+			return fmt.Errorf("brew pin %s failed: %w", pkg.Name, err)
+		}
+		fmt.Printf("✓ - %s is held: pinned\n", pkg.Name)
+	}
+	return nil
+}
+
+func kindFlag(isCask bool) string {
+	if isCask {
+		return "--cask"
+	}
+	return "--formula"
 }
@@ -0,0 +1,29 @@
+package actual
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		installed, current string
+		want               Bump
+	}{
+		{"1.24.5", "1.24.6", BumpPatch},
+		{"1.24.5", "1.25.0", BumpMinor},
+		{"22.11.0", "23.3.0", BumpMajor},
+		{"0.17.1", "0.18.0", BumpMajor}, // 0.x minors may break
+		{"0.17.1", "0.17.2", BumpPatch},
+		{"3.4.0", "3.4.0_1", BumpPatch}, // new revision
+		{"1.2_1", "2.0", BumpMajor},
+		{"3", "3.1", BumpMinor},
+		{"1.5.2,2024.10", "1.5.3,2024.11", BumpPatch}, // cask build suffix
+		{"131.0.6778.86", "latest", BumpUnknown},
+		{"latest", "latest", BumpUnknown},
+		{"", "1.0.0", BumpUnknown},
+		{"1.0.0", "", BumpUnknown},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.installed, tt.current); got != tt.want {
+			t.Errorf("Classify(%q, %q) = %s, want %s", tt.installed, tt.current, got, tt.want)
+		}
+	}
+}
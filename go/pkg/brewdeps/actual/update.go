@@ -0,0 +1,59 @@
+package actual
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+)
+
+// updateKey is the cache entry recording when brew update last ran
+const updateKey = "brew/last-update"
+
+// UpdateOptions controls when Update runs brew update, which is slow and needs the network
+type UpdateOptions struct {
+	// Skip never runs brew update (--no-update, --offline)
+	Skip bool
+	// Interval throttles brew update: it only runs when the last update is older (0: always)
+	Interval time.Duration
+}
+
+// Update runs brew update, so that the outdated flags of brew info are current:
+// it must run before collecting the actual state (see GetActual).
+// It returns when the formula index was last updated (zero if unknown), which the
+// store records, so that brew update is skipped while the index is fresh (see UpdateOptions).
+func Update(store cache.Store, opts UpdateOptions) (time.Time, error) {
+	var last time.Time
+	entry, err := store.Read(updateKey)
+	switch {
+	case err == nil:
+		last = entry.FetchedAt
+	case !errors.Is(err, cache.ErrNotCached):
+		return time.Time{}, err
+	}
+	if !needsUpdate(last, opts, time.Now()) {
+		return last, nil
+	}
+
+	if err := exec.Command("brew", "update").Run(); err != nil {
+		return last, fmt.Errorf("brew update failed: %w", err)
+	}
+	now := time.Now()
+	if err := store.Write(updateKey, cache.Entry{FetchedAt: now}); err != nil {
+		return now, err
+	}
+	return now, nil
+}
+
+// needsUpdate tells whether brew update must run, given when it last did
+func needsUpdate(last time.Time, opts UpdateOptions, now time.Time) bool {
+	switch {
+	case opts.Skip:
+		return false
+	case last.IsZero(), opts.Interval <= 0:
+		return true
+	}
+	return now.Sub(last) >= opts.Interval
+}
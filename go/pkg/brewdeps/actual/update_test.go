@@ -0,0 +1,27 @@
+package actual
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNeedsUpdate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		last time.Time
+		opts UpdateOptions
+		want bool
+	}{
+		{"never updated", time.Time{}, UpdateOptions{Interval: 6 * time.Hour}, true},
+		{"fresh", now.Add(-2 * time.Hour), UpdateOptions{Interval: 6 * time.Hour}, false},
+		{"stale", now.Add(-6 * time.Hour), UpdateOptions{Interval: 6 * time.Hour}, true},
+		{"no throttle", now.Add(-time.Minute), UpdateOptions{}, true},
+		{"skipped", time.Time{}, UpdateOptions{Skip: true, Interval: 6 * time.Hour}, false},
+	}
+	for _, tt := range tests {
+		if got := needsUpdate(tt.last, tt.opts, now); got != tt.want {
+			t.Errorf("%s: needsUpdate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+// Kind tells why a package is installed
+type Kind string
+
+const (
+	// Configured packages are listed in the config (the roots)
+	Configured Kind = "configured"
+	// Dependency packages are required, transitively, by configured ones
+	Dependency Kind = "dependency"
+	// Extraneous packages are required by no configured package
+	Extraneous Kind = "extraneous"
+)
+
+// colors are the fill colors of each kind, in DOT and Mermaid
+var colors = map[Kind]string{
+	Configured: "#a6d96a",
+	Dependency: "#e0e0e0",
+	Extraneous: "#f4a582",
+}
+
+// Formats are the formats Render supports
+var Formats = []string{"dot", "mermaid", "json"}
+
+// Classify returns the kind of every package, given the configured (required) packages
+func (g *Graph) Classify(required []types.Package) map[types.Package]Kind {
+	closure := g.Closure(required)
+	configured := make(Set)
+	for _, pkg := range required {
+		configured[pkg] = true
+	}
+	kinds := make(map[types.Package]Kind)
+	for _, pkg := range g.packages {
+		switch {
+		case configured[pkg]:
+			kinds[pkg] = Configured
+		case closure[pkg]:
+			kinds[pkg] = Dependency
+		default:
+			kinds[pkg] = Extraneous
+		}
+	}
+	return kinds
+}
+
+// Ancestors returns the packages depending on a package, transitively, itself included
+func (g *Graph) Ancestors(pkg types.Package) Set {
+	ancestors := Set{pkg: true}
+	queue := []types.Package{pkg}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.dependents[p] {
+			if !ancestors[dependent] {
+				ancestors[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return ancestors
+}
+
+// Around returns the subgraph around a package: what it depends on, and what depends on it
+func (g *Graph) Around(pkg types.Package) Set {
+	around := g.Ancestors(pkg)
+	for p := range g.Descendants(pkg) {
+		around[p] = true
+	}
+	return around
+}
+
+// Render writes the graph in a format ("dot", "mermaid" or "json"), with packages
+// colored by kind (see Classify). When only is not nil, only its packages are rendered.
+func (g *Graph) Render(w io.Writer, format string, kinds map[types.Package]Kind, only Set) error {
+	var nodes []types.Package
+	for _, pkg := range g.packages {
+		if only == nil || only[pkg] {
+			nodes = append(nodes, pkg)
+		}
+	}
+	var edges [][2]types.Package
+	for _, pkg := range nodes {
+		for _, dep := range g.deps[pkg] {
+			if only == nil || only[dep] {
+				edges = append(edges, [2]types.Package{pkg, dep})
+			}
+		}
+	}
+
+	switch format {
+	case "dot":
+		return renderDOT(w, nodes, edges, kinds)
+	case "mermaid":
+		return renderMermaid(w, nodes, edges, kinds)
+	case "json":
+		return renderJSON(w, nodes, edges, kinds)
+	}
+	return fmt.Errorf("unknown graph format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// renderDOT renders for Graphviz: casks are boxes, formulae ellipses
+func renderDOT(w io.Writer, nodes []types.Package, edges [][2]types.Package, kinds map[types.Package]Kind) error {
+	var b strings.Builder
+	b.WriteString("digraph brew {\n  rankdir=LR;\n  node [style=filled];\n")
+	for _, pkg := range nodes {
+		shape := "ellipse"
+		if pkg.IsCask {
+			shape = "box"
+		}
+		fmt.Fprintf(&b, "  %q [shape=%s, fillcolor=%q, tooltip=%q];\n", pkg.Name, shape, colors[kinds[pkg]], kinds[pkg])
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", e[0].Name, e[1].Name)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderMermaid renders a Mermaid flowchart: casks are rectangles, formulae rounded
+func renderMermaid(w io.Writer, nodes []types.Package, edges [][2]types.Package, kinds map[types.Package]Kind) error {
+	ids := make(map[types.Package]string)
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i, pkg := range nodes {
+		ids[pkg] = fmt.Sprintf("n%d", i)
+		left, right := "(", ")"
+		if pkg.IsCask {
+			left, right = "[", "]"
+		}
+		fmt.Fprintf(&b, "  %s%s\"%s\"%s:::%s\n", ids[pkg], left, pkg.Name, right, kinds[pkg])
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e[0]], ids[e[1]])
+	}
+	for _, kind := range []Kind{Configured, Dependency, Extraneous} {
+		fmt.Fprintf(&b, "  classDef %s fill:%s\n", kind, colors[kind])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	Name string `json:"name"`
+	Cask bool   `json:"cask"`
+	Kind Kind   `json:"kind"`
+}
+
+type jsonEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func renderJSON(w io.Writer, nodes []types.Package, edges [][2]types.Package, kinds map[types.Package]Kind) error {
+	out := jsonGraph{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+	for _, pkg := range nodes {
+		out.Nodes = append(out.Nodes, jsonNode{Name: pkg.Name, Cask: pkg.IsCask, Kind: kinds[pkg]})
+	}
+	for _, e := range edges {
+		out.Edges = append(out.Edges, jsonEdge{From: e[0].Name, To: e[1].Name})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
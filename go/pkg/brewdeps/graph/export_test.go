@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+func TestRender(t *testing.T) {
+	p := func(name string) types.Package { return types.Package{Name: name} }
+	vlc := types.Package{Name: "vlc", IsCask: true}
+	g := New([]types.Package{p("wget"), p("openssl@3"), vlc}, map[types.Package][]types.Package{
+		p("wget"): pkgs("openssl@3"),
+	})
+	kinds := g.Classify(pkgs("wget"))
+
+	tests := []struct {
+		format string
+		only   Set
+		want   []string
+	}{
+		{format: "dot", want: []string{
+			`"wget" [shape=ellipse, fillcolor="#a6d96a", tooltip="configured"];`,
+			`"openssl@3" [shape=ellipse, fillcolor="#e0e0e0", tooltip="dependency"];`,
+			`"vlc" [shape=box, fillcolor="#f4a582", tooltip="extraneous"];`,
+			`"wget" -> "openssl@3";`,
+		}},
+		{format: "mermaid", want: []string{
+			`n0("wget"):::configured`,
+			`n2["vlc"]:::extraneous`,
+			`n0 --> n1`,
+			`classDef extraneous fill:#f4a582`,
+		}},
+		{format: "json", want: []string{
+			`"name": "openssl@3",`,
+			`"kind": "dependency"`,
+			`"from": "wget",`,
+		}},
+		{format: "dot", only: g.Around(p("openssl@3")), want: []string{
+			`"wget" -> "openssl@3";`,
+		}},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := g.Render(&b, tt.format, kinds, tt.only); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("Render(%s) is missing %q:\n%s", tt.format, want, b.String())
+			}
+		}
+		if tt.only != nil && strings.Contains(b.String(), "vlc") {
+			t.Errorf("Render(%s) around openssl@3 contains vlc:\n%s", tt.format, b.String())
+		}
+	}
+
+	if err := g.Render(&bytes.Buffer{}, "svg", kinds, nil); err == nil {
+		t.Errorf("Render(svg) = nil, want an error")
+	}
+}
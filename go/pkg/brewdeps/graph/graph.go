@@ -0,0 +1,238 @@
+// Package graph holds the dependency graph of installed brew packages:
+// edges go from a package to its dependencies (see types.ActualState.DepsMap).
+//
+// Closures are computed once per strongly connected component, in dependency
+// order, so that cycles (which brew should not have, but may) neither loop
+// forever nor cost more than a single traversal.
+package graph
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+// Set is a set of packages
+type Set map[types.Package]bool
+
+// Graph is the dependency graph of installed packages
+type Graph struct {
+	// packages are the nodes, in the order of the installed packages,
+	// followed by dependencies that are not installed
+	packages   []types.Package
+	deps       map[types.Package][]types.Package
+	dependents map[types.Package][]types.Package
+
+	// component maps every package to its strongly connected component,
+	// components are numbered in dependency order (dependencies first)
+	component  map[types.Package]int
+	components [][]types.Package
+	// descendants memoizes, per component, every package reachable from it (itself included)
+	descendants []Set
+}
+
+// New builds the graph of the installed packages and their dependencies
+func New(installed []types.Package, depsMap map[types.Package][]types.Package) *Graph {
+	g := &Graph{
+		deps:       make(map[types.Package][]types.Package),
+		dependents: make(map[types.Package][]types.Package),
+	}
+	seen := make(Set)
+	add := func(pkg types.Package) {
+		if !seen[pkg] {
+			seen[pkg] = true
+			g.packages = append(g.packages, pkg)
+		}
+	}
+	for _, pkg := range installed {
+		add(pkg)
+	}
+	for _, pkg := range installed {
+		for _, dep := range depsMap[pkg] {
+			add(dep)
+			g.deps[pkg] = append(g.deps[pkg], dep)
+			g.dependents[dep] = append(g.dependents[dep], pkg)
+		}
+	}
+	g.computeComponents()
+	return g
+}
+
+// Packages returns every package of the graph
+func (g *Graph) Packages() []types.Package {
+	return g.packages
+}
+
+// Deps returns the direct dependencies of a package
+func (g *Graph) Deps(pkg types.Package) []types.Package {
+	return g.deps[pkg]
+}
+
+// Dependents returns the packages that directly depend on a package
+func (g *Graph) Dependents(pkg types.Package) []types.Package {
+	return g.dependents[pkg]
+}
+
+// Descendants returns the packages reachable from a package, itself included.
+// The set is memoized: it must not be modified.
+func (g *Graph) Descendants(pkg types.Package) Set {
+	c, ok := g.component[pkg]
+	if !ok {
+		return Set{pkg: true}
+	}
+	return g.descendants[c]
+}
+
+// Closure returns the packages reachable from the roots, roots included:
+// the packages required, directly or transitively, by the roots
+func (g *Graph) Closure(roots []types.Package) Set {
+	closure := make(Set)
+	for _, root := range roots {
+		if closure[root] {
+			continue
+		}
+		for pkg := range g.Descendants(root) {
+			closure[pkg] = true
+		}
+	}
+	return closure
+}
+
+// maxPaths bounds the paths explored by Paths, whose number can grow exponentially
+const maxPaths = 1000
+
+// Paths returns the dependency paths from one package to another, both included,
+// at most limit of them (shortest first). Only dependencies that can reach the
+// target are explored, and a path never visits a package twice.
+func (g *Graph) Paths(from, to types.Package, limit int) [][]types.Package {
+	var paths [][]types.Package
+	onPath := make(Set)
+	var path []types.Package
+	var walk func(pkg types.Package)
+	walk = func(pkg types.Package) {
+		path = append(path, pkg)
+		onPath[pkg] = true
+		if pkg == to {
+			paths = append(paths, slices.Clone(path))
+		} else {
+			for _, dep := range g.deps[pkg] {
+				if len(paths) < maxPaths && !onPath[dep] && g.Descendants(dep)[to] {
+					walk(dep)
+				}
+			}
+		}
+		onPath[pkg] = false
+		path = path[:len(path)-1]
+	}
+	if g.Descendants(from)[to] {
+		walk(from)
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	if len(paths) > limit {
+		paths = paths[:limit]
+	}
+	return paths
+}
+
+// SameComponent reports whether two packages depend on each other (through a cycle)
+func (g *Graph) SameComponent(a, b types.Package) bool {
+	ca, okA := g.component[a]
+	cb, okB := g.component[b]
+	return okA && okB && ca == cb
+}
+
+// Cycles returns the dependency cycles: the components of more than one package,
+// or of a package depending on itself
+func (g *Graph) Cycles() [][]types.Package {
+	var cycles [][]types.Package
+	for _, members := range g.components {
+		if len(members) > 1 || containsPackage(g.deps[members[0]], members[0]) {
+			cycles = append(cycles, members)
+		}
+	}
+	return cycles
+}
+
+// Order returns every package, dependencies before their dependents
+// (the packages of a cycle are adjacent, in no particular order)
+func (g *Graph) Order() []types.Package {
+	var order []types.Package
+	for _, members := range g.components {
+		order = append(order, members...)
+	}
+	return order
+}
+
+// computeComponents finds the strongly connected components (Tarjan's algorithm),
+// which come out in dependency order, and memoizes their descendants along the way
+func (g *Graph) computeComponents() {
+	g.component = make(map[types.Package]int)
+	index := make(map[types.Package]int)
+	lowlink := make(map[types.Package]int)
+	onStack := make(Set)
+	var stack []types.Package
+
+	var visit func(pkg types.Package)
+	visit = func(pkg types.Package) {
+		index[pkg] = len(index)
+		lowlink[pkg] = index[pkg]
+		stack = append(stack, pkg)
+		onStack[pkg] = true
+
+		for _, dep := range g.deps[pkg] {
+			if _, visited := index[dep]; !visited {
+				visit(dep)
+				lowlink[pkg] = min(lowlink[pkg], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[pkg] = min(lowlink[pkg], index[dep])
+			}
+		}
+
+		if lowlink[pkg] != index[pkg] {
+			return
+		}
+		// pkg is the root of a component: pop its members
+		c := len(g.components)
+		var members []types.Package
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			g.component[top] = c
+			members = append(members, top)
+			if top == pkg {
+				break
+			}
+		}
+		// the components of its dependencies were all numbered before this one
+		descendants := make(Set)
+		for _, member := range members {
+			descendants[member] = true
+			for _, dep := range g.deps[member] {
+				if g.component[dep] != c {
+					for d := range g.descendants[g.component[dep]] {
+						descendants[d] = true
+					}
+				}
+			}
+		}
+		g.components = append(g.components, members)
+		g.descendants = append(g.descendants, descendants)
+	}
+
+	for _, pkg := range g.packages {
+		if _, visited := index[pkg]; !visited {
+			visit(pkg)
+		}
+	}
+}
+
+func containsPackage(s []types.Package, e types.Package) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+func pkgs(names ...string) []types.Package {
+	var ps []types.Package
+	for _, name := range names {
+		ps = append(ps, types.Package{Name: name})
+	}
+	return ps
+}
+
+func names(set Set) []string {
+	var ns []string
+	for pkg := range set {
+		ns = append(ns, pkg.Name)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+// wget -> openssl@3 -> ca-certificates, a <-> b -> c, and d alone
+func testGraph() *Graph {
+	p := func(name string) types.Package { return types.Package{Name: name} }
+	return New(pkgs("wget", "openssl@3", "ca-certificates", "a", "b", "c", "d"), map[types.Package][]types.Package{
+		p("wget"):      pkgs("openssl@3"),
+		p("openssl@3"): pkgs("ca-certificates"),
+		p("a"):         pkgs("b"),
+		p("b"):         pkgs("a", "c"),
+	})
+}
+
+func TestClosure(t *testing.T) {
+	g := testGraph()
+	tests := []struct {
+		roots []string
+		want  []string
+	}{
+		{roots: []string{"wget"}, want: []string{"ca-certificates", "openssl@3", "wget"}},
+		{roots: []string{"openssl@3", "d"}, want: []string{"ca-certificates", "d", "openssl@3"}},
+		{roots: []string{"b"}, want: []string{"a", "b", "c"}},
+		{roots: []string{"not-installed"}, want: []string{"not-installed"}},
+	}
+	for _, tt := range tests {
+		if got := names(g.Closure(pkgs(tt.roots...))); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Closure(%v) = %v, want %v", tt.roots, got, tt.want)
+		}
+	}
+}
+
+func TestDependents(t *testing.T) {
+	g := testGraph()
+	if got, want := g.Dependents(types.Package{Name: "openssl@3"}), pkgs("wget"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(openssl@3) = %v, want %v", got, want)
+	}
+	if got := g.Dependents(types.Package{Name: "wget"}); len(got) != 0 {
+		t.Errorf("Dependents(wget) = %v, want none", got)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := testGraph()
+	cycles := g.Cycles()
+	if len(cycles) != 1 {
+		t.Fatalf("Cycles() = %v, want one cycle", cycles)
+	}
+	got := Set{}
+	for _, pkg := range cycles[0] {
+		got[pkg] = true
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(names(got), want) {
+		t.Errorf("Cycles() = %v, want %v", names(got), want)
+	}
+	if !g.SameComponent(types.Package{Name: "a"}, types.Package{Name: "b"}) {
+		t.Errorf("SameComponent(a, b) = false, want true")
+	}
+}
+
+func TestOrder(t *testing.T) {
+	g := testGraph()
+	position := make(map[string]int)
+	for i, pkg := range g.Order() {
+		position[pkg.Name] = i
+	}
+	for _, edge := range [][2]string{{"wget", "openssl@3"}, {"openssl@3", "ca-certificates"}, {"b", "c"}} {
+		if position[edge[1]] > position[edge[0]] {
+			t.Errorf("Order(): %s comes before its dependency %s", edge[0], edge[1])
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	p := func(name string) types.Package { return types.Package{Name: name} }
+	// ffmpeg -> x264 -> openssl@3, ffmpeg -> openssl@3
+	g := New(pkgs("ffmpeg", "x264", "openssl@3"), map[types.Package][]types.Package{
+		p("ffmpeg"): pkgs("x264", "openssl@3"),
+		p("x264"):   pkgs("openssl@3"),
+	})
+	got := g.Paths(p("ffmpeg"), p("openssl@3"), 10)
+	want := [][]types.Package{pkgs("ffmpeg", "openssl@3"), pkgs("ffmpeg", "x264", "openssl@3")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+	if got := g.Paths(p("ffmpeg"), p("openssl@3"), 1); len(got) != 1 {
+		t.Errorf("Paths(limit 1) = %v, want one path", got)
+	}
+	if got := g.Paths(p("x264"), p("ffmpeg"), 10); len(got) != 0 {
+		t.Errorf("Paths(x264, ffmpeg) = %v, want none", got)
+	}
+}
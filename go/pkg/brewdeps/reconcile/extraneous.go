@@ -2,8 +2,8 @@ package reconcile
 
 import (
 	"fmt"
-	"log"
 
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/graph"
 	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
 	"github.com/daneroo/dotfiles/go/pkg/config"
 )
@@ -11,11 +11,17 @@ import (
 var verbose bool
 
 // Extraneous returns a list of installed packages that are not required (directly or transitively).
-func Extraneous(required, installed []types.Package, depsMap map[types.Package][]types.Package) []types.Package {
+// The required closure is computed once, from the dependency graph.
+func Extraneous(required, installed []types.Package, g *graph.Graph) []types.Package {
+	return minimizeExtraneous(allExtraneous(required, installed, g), g)
+}
+
+// allExtraneous returns every installed package that is not required, roots and their dependencies
+func allExtraneous(required, installed []types.Package, g *graph.Graph) []types.Package {
+	closure := g.Closure(required)
 	extra := []types.Package{}
 	for _, inst := range installed {
-		ok := IsTransitiveDep(inst, required, depsMap)
-		if ok {
+		if closure[inst] {
 			if config.Global.Verbose {
 				fmt.Printf(" - %s is required (directly or transitively)\n", inst.Name)
 			}
@@ -26,35 +32,102 @@ func Extraneous(required, installed []types.Package, depsMap map[types.Package][
 			}
 		}
 	}
-
-	minimalExtra := minimizeExtraneous(extra, depsMap)
-	if len(minimalExtra) == 0 && len(extra) > 0 {
-		log.Fatal("Impossible: found extraneous packages but no minimal set - circular dependencies?")
-	}
-	return minimalExtra
+	return extra
 }
 
-func minimizeExtraneous(extra []types.Package, depsMap map[types.Package][]types.Package) []types.Package {
-	isDependency := make(map[types.Package]bool)
+// minimizeExtraneous keeps the extraneous packages that no other extraneous package depends on:
+// uninstalling them orphans the others. Packages in a dependency cycle depend on each other,
+// so the first one of a cycle is kept, unless a package outside the cycle depends on it.
+func minimizeExtraneous(extra []types.Package, g *graph.Graph) []types.Package {
+	isExtra := make(graph.Set)
 	for _, pkg := range extra {
-		if pkgDeps, ok := depsMap[pkg]; ok {
-			for _, dep := range pkgDeps {
-				if ContainsPackage(extra, dep) {
-					isDependency[dep] = true
-				}
-			}
-		}
+		isExtra[pkg] = true
 	}
 
 	var roots []types.Package
 	for _, pkg := range extra {
-		if !isDependency[pkg] {
-			roots = append(roots, pkg)
-		} else {
+		if dependent, ok := extraDependent(pkg, isExtra, g); ok {
 			if config.Global.Verbose {
-				fmt.Printf(" - %s is a dependency of another extraneous package, so skipping\n", pkg.Name)
+				fmt.Printf(" - %s is a dependency of another extraneous package (%s), so skipping\n", pkg.Name, dependent.Name)
 			}
+			continue
 		}
+		if cycleRoot := firstInCycle(pkg, roots, g); cycleRoot != nil {
+			if config.Global.Verbose {
+				fmt.Printf(" - %s is in a dependency cycle with %s, so skipping\n", pkg.Name, cycleRoot.Name)
+			}
+			continue
+		}
+		roots = append(roots, pkg)
 	}
 	return roots
 }
+
+// extraDependent returns an extraneous package outside the cycle of pkg (if any) depending on pkg
+func extraDependent(pkg types.Package, isExtra graph.Set, g *graph.Graph) (types.Package, bool) {
+	for _, dependent := range g.Dependents(pkg) {
+		if isExtra[dependent] && !g.SameComponent(dependent, pkg) {
+			return dependent, true
+		}
+	}
+	return types.Package{}, false
+}
+
+// firstInCycle returns the root already kept for the cycle of pkg, if any
+func firstInCycle(pkg types.Package, roots []types.Package, g *graph.Graph) *types.Package {
+	for i := range roots {
+		if g.SameComponent(roots[i], pkg) {
+			return &roots[i]
+		}
+	}
+	return nil
+}
+
+// UninstallPlan removes every extraneous package, not only the roots (see Extraneous):
+// the dependencies only they need would otherwise be left behind as orphans, for the next run.
+type UninstallPlan struct {
+	// Roots are the extraneous packages no other extraneous package depends on
+	Roots []types.Package
+	// Orphans are the dependencies of the roots, needed by no configured package,
+	// which uninstalling the roots leaves orphaned; HeldBy maps them to the roots needing them
+	Orphans []types.Package
+	HeldBy  map[types.Package][]types.Package
+	// Order holds roots and orphans, dependents before their dependencies
+	Order []types.Package
+}
+
+// PlanUninstall plans the removal of every extraneous package
+func PlanUninstall(required, installed []types.Package, g *graph.Graph) UninstallPlan {
+	extra := allExtraneous(required, installed, g)
+	plan := UninstallPlan{
+		Roots:  minimizeExtraneous(extra, g),
+		HeldBy: make(map[types.Package][]types.Package),
+	}
+	isExtra := make(graph.Set)
+	isRoot := make(graph.Set)
+	for _, pkg := range extra {
+		isExtra[pkg] = true
+	}
+	for _, pkg := range plan.Roots {
+		isRoot[pkg] = true
+	}
+	for _, pkg := range extra {
+		if isRoot[pkg] {
+			continue
+		}
+		plan.Orphans = append(plan.Orphans, pkg)
+		for _, root := range plan.Roots {
+			if g.Descendants(root)[pkg] {
+				plan.HeldBy[pkg] = append(plan.HeldBy[pkg], root)
+			}
+		}
+	}
+	// g.Order has dependencies first: reversed, dependents come first
+	order := g.Order()
+	for i := len(order) - 1; i >= 0; i-- {
+		if isExtra[order[i]] {
+			plan.Order = append(plan.Order, order[i])
+		}
+	}
+	return plan
+}
@@ -0,0 +1,87 @@
+package reconcile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/actual"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/graph"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+func TestExtraneous(t *testing.T) {
+	p := func(name string) types.Package { return types.Package{Name: name} }
+	installed := []types.Package{p("wget"), p("openssl@3"), p("ffmpeg"), p("x264"), p("a"), p("b"), p("vlc")}
+	installed[6].IsCask = true
+	depsMap := map[types.Package][]types.Package{
+		p("wget"):   {p("openssl@3")},
+		p("ffmpeg"): {p("x264"), p("openssl@3")},
+		p("a"):      {p("b")},
+		p("b"):      {p("a")},
+	}
+	g := graph.New(installed, depsMap)
+
+	got := Extraneous([]types.Package{p("wget")}, installed, g)
+	want := []types.Package{p("ffmpeg"), p("a"), {Name: "vlc", IsCask: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extraneous() = %v, want %v", got, want)
+	}
+}
+
+func TestPlanUninstall(t *testing.T) {
+	p := func(name string) types.Package { return types.Package{Name: name} }
+	vlc := types.Package{Name: "vlc", IsCask: true}
+	// wget (configured) -> openssl@3, ffmpeg -> x264 -> lame, ffmpeg -> openssl@3, vlc -> lame
+	installed := []types.Package{p("lame"), p("wget"), p("openssl@3"), p("x264"), p("ffmpeg"), vlc}
+	depsMap := map[types.Package][]types.Package{
+		p("wget"):   {p("openssl@3")},
+		p("ffmpeg"): {p("x264"), p("openssl@3")},
+		p("x264"):   {p("lame")},
+		vlc:         {p("lame")},
+	}
+	plan := PlanUninstall([]types.Package{p("wget")}, installed, graph.New(installed, depsMap))
+
+	if want := []types.Package{p("ffmpeg"), vlc}; !reflect.DeepEqual(plan.Roots, want) {
+		t.Errorf("Roots = %v, want %v", plan.Roots, want)
+	}
+	if want := []types.Package{p("lame"), p("x264")}; !reflect.DeepEqual(plan.Orphans, want) {
+		t.Errorf("Orphans = %v, want %v", plan.Orphans, want)
+	}
+	if want := []types.Package{p("ffmpeg"), vlc}; !reflect.DeepEqual(plan.HeldBy[p("lame")], want) {
+		t.Errorf("HeldBy[lame] = %v, want %v", plan.HeldBy[p("lame")], want)
+	}
+
+	// dependents before dependencies; openssl@3 is still needed by wget
+	position := make(map[types.Package]int)
+	for i, pkg := range plan.Order {
+		position[pkg] = i
+	}
+	if len(plan.Order) != 4 {
+		t.Fatalf("Order = %v, want 4 packages", plan.Order)
+	}
+	for _, edge := range [][2]types.Package{{p("ffmpeg"), p("x264")}, {p("x264"), p("lame")}, {vlc, p("lame")}} {
+		if position[edge[0]] > position[edge[1]] {
+			t.Errorf("Order = %v: %s must come before %s", plan.Order, edge[0].Name, edge[1].Name)
+		}
+	}
+}
+
+func TestPlanUninstallKindMismatch(t *testing.T) {
+	// fuse-t-sshfs (configured) declares a vlc formula, installed as a cask
+	vlc := types.Package{Name: "vlc", IsCask: true}
+	sshfs := types.Package{Name: "fuse-t-sshfs", IsCask: true}
+	state := types.ActualState{
+		Packages: []types.Package{vlc, sshfs},
+		DepsMap:  map[types.Package][]types.Package{vlc: nil, sshfs: {{Name: "vlc"}}},
+	}
+	broken, err := actual.Validate(state.Packages, state.DepsMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken.Repair(state)
+
+	plan := PlanUninstall([]types.Package{sshfs}, state.Packages, graph.New(state.Packages, state.DepsMap))
+	if len(plan.Order) != 0 {
+		t.Errorf("Order = %v, want none: vlc is held by fuse-t-sshfs", plan.Order)
+	}
+}
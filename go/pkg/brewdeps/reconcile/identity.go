@@ -0,0 +1,68 @@
+package reconcile
+
+import (
+	"fmt"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+// Rename is a configured package that brew knows by another name
+type Rename struct {
+	Configured types.Package
+	Canonical  types.Package
+	// Match is how the configured name resolved: types.Alias or types.OldName
+	Match types.Match
+}
+
+// Canonicalize names the configured packages like the installed packages they designate
+// (see types.ActualState.Canonical), so that they compare with the actual state:
+// brew installs "speedtest" as "teamookla/speedtest/speedtest", and "ipfs" as "kubo".
+// Packages that are not installed keep their configured name, and a package configured
+// under two names is kept once.
+//
+// It also returns the configured aliases and old names, which the config should replace.
+func Canonicalize(desired []types.Package, actualState types.ActualState) ([]types.Package, []Rename) {
+	var canonical []types.Package
+	var renames []Rename
+	seen := make(map[types.Package]bool)
+	for _, pkg := range desired {
+		c, match := actualState.Canonical(pkg)
+		switch match {
+		case types.NotInstalled:
+			c = pkg
+		case types.Alias, types.OldName:
+			renames = append(renames, Rename{Configured: pkg, Canonical: c, Match: match})
+		}
+		if !seen[c] {
+			seen[c] = true
+			canonical = append(canonical, c)
+		}
+	}
+	return canonical, renames
+}
+
+// canonicalSections keys the config sections by canonical name (see Canonicalize)
+func canonicalSections(sections map[types.Package]string, actualState types.ActualState) map[types.Package]string {
+	canonical := make(map[types.Package]string, len(sections))
+	for pkg, section := range sections {
+		c, match := actualState.Canonical(pkg)
+		if match == types.NotInstalled {
+			c = pkg
+		}
+		canonical[c] = section
+	}
+	return canonical
+}
+
+// reportRenames warns about packages configured by an alias or an old name:
+// brew still accepts them, but an old name may be reused, or dropped, by its tap
+func reportRenames(renames []Rename) {
+	for _, r := range renames {
+		switch r.Match {
+		case types.OldName:
+			fmt.Printf("△ - %s was renamed to %s: update the config\n", r.Configured.Name, r.Canonical.Name)
+		default:
+			fmt.Printf("△ - %s is an %s of %s: use its name in the config\n", r.Configured.Name, r.Match, r.Canonical.Name)
+		}
+	}
+}
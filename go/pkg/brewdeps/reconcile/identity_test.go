@@ -0,0 +1,53 @@
+package reconcile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+func TestCanonicalize(t *testing.T) {
+	p := func(name string) types.Package { return types.Package{Name: name} }
+	speedtest := p("teamookla/speedtest/speedtest")
+	vlc := types.Package{Name: "vlc", IsCask: true}
+	state := types.ActualState{
+		Packages: []types.Package{p("wget"), p("kubo"), p("python@3.13"), speedtest, vlc},
+		Info: map[types.Package]types.PackageInfo{
+			p("wget"):        {Tap: "homebrew/core"},
+			p("kubo"):        {Tap: "homebrew/core", OldNames: []string{"ipfs"}},
+			p("python@3.13"): {Tap: "homebrew/core", Aliases: []string{"python3", "python"}},
+			speedtest:        {Tap: "teamookla/speedtest"},
+			vlc:              {Tap: "homebrew/cask"},
+		},
+	}
+
+	got, renames := Canonicalize([]types.Package{
+		p("homebrew/core/wget"),      // qualified core name
+		p("speedtest"),               // short name
+		p("ipfs"),                    // old name
+		p("kubo"),                    // the same package, under its name
+		p("python3"),                 // alias
+		p("yq"),                      // not installed
+		{Name: "wget", IsCask: true}, // no such cask
+	}, state)
+
+	want := []types.Package{p("wget"), speedtest, p("kubo"), p("python@3.13"), p("yq"), {Name: "wget", IsCask: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Canonicalize() = %v, want %v", got, want)
+	}
+	wantRenames := []Rename{
+		{Configured: p("ipfs"), Canonical: p("kubo"), Match: types.OldName},
+		{Configured: p("python3"), Canonical: p("python@3.13"), Match: types.Alias},
+	}
+	if !reflect.DeepEqual(renames, wantRenames) {
+		t.Errorf("renames = %v, want %v", renames, wantRenames)
+	}
+
+	// a short name shared by two taps is ambiguous, as it is for brew
+	state.Packages = append(state.Packages, p("other/tap/speedtest"))
+	state.Info[p("other/tap/speedtest")] = types.PackageInfo{Tap: "other/tap"}
+	if got, _ := Canonicalize([]types.Package{p("speedtest")}, state); !reflect.DeepEqual(got, []types.Package{p("speedtest")}) {
+		t.Errorf("Canonicalize(ambiguous) = %v, want it unchanged", got)
+	}
+}
@@ -0,0 +1,151 @@
+package reconcile
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/actual"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/graph"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+	"github.com/daneroo/dotfiles/go/pkg/config"
+)
+
+// OutdatedGate sorts the outdated packages by the policy that applies to them (see config.BrewOutdated)
+type OutdatedGate struct {
+	// Abort, Warn and Upgrade are the outdated packages required by the config, by policy
+	Abort   []actual.OutdatedPackage
+	Warn    []actual.OutdatedPackage
+	Upgrade []actual.OutdatedPackage
+	// Held are outdated packages kept at their version: held in the config, or pinned
+	Held []actual.OutdatedPackage
+	// Unrelated are outdated packages no configured package requires (extraneous)
+	Unrelated []actual.OutdatedPackage
+	// Unpinned are the held formulae brew does not pin yet (casks cannot be pinned)
+	Unpinned []types.Package
+	// IndexUpdated is when the formula index was last updated (see actual.Update), zero if unknown:
+	// packages are only known to be outdated as of then
+	IndexUpdated time.Time
+}
+
+// staleIndex is the age past which the outdated report is not trusted
+const staleIndex = 7 * 24 * time.Hour
+
+// GateOutdated applies the outdated policy to the outdated packages of the actual state.
+// Packages required by the config, directly or as a dependency, get their own policy,
+// or else the global one; held, pinned and unrelated packages never stop the reconciliation.
+func GateOutdated(outdated actual.OutdatedPackages, desired []types.Package, policy config.BrewOutdated, actualState types.ActualState) OutdatedGate {
+	policies := make(map[types.Package]string)
+	for name, p := range policy.Packages {
+		for _, pkg := range []types.Package{{Name: name}, {Name: name, IsCask: true}} {
+			if canonical, match := actualState.Canonical(pkg); match != types.NotInstalled {
+				policies[canonical] = p
+			}
+		}
+	}
+
+	var gate OutdatedGate
+	for _, pkg := range actualState.Packages {
+		if policies[pkg] == config.OutdatedHold && !pkg.IsCask && !actualState.Info[pkg].Pinned {
+			gate.Unpinned = append(gate.Unpinned, pkg)
+		}
+	}
+
+	desired, _ = Canonicalize(desired, actualState)
+	required := graph.New(actualState.Packages, actualState.DepsMap).Closure(desired)
+	for _, p := range append(outdated.Formulae, outdated.Casks...) {
+		switch {
+		case policies[p.Package] == config.OutdatedHold, p.Pinned:
+			gate.Held = append(gate.Held, p)
+		case !required[p.Package]:
+			gate.Unrelated = append(gate.Unrelated, p)
+		default:
+			switch cmp.Or(policies[p.Package], policy.Policy, config.OutdatedAbort) {
+			case config.OutdatedWarn:
+				gate.Warn = append(gate.Warn, p)
+			case config.OutdatedUpgrade:
+				gate.Upgrade = append(gate.Upgrade, p)
+			default:
+				gate.Abort = append(gate.Abort, p)
+			}
+		}
+	}
+	return gate
+}
+
+// Report shows the outdated packages by policy, and returns true if the
+// reconciliation must stop: outdated packages can break dependency resolution.
+// Every upgrade is classified (see actual.Classify), and major ones are counted
+// in the headers: they are the most likely to break.
+// Packages to upgrade are only listed: see actual.Upgrade.
+func (g OutdatedGate) Report() bool {
+	reportIndexAge(g.IndexUpdated, time.Now())
+	if len(g.Abort)+len(g.Warn)+len(g.Upgrade)+len(g.Held)+len(g.Unrelated) == 0 {
+		fmt.Printf("✓ - All formulae and casks are up to date\n")
+		return false
+	}
+	if len(g.Abort) > 0 {
+		fmt.Printf("✗ - Updates available: (%d packages%s)\n", len(g.Abort), majors(g.Abort))
+		showOutdated(g.Abort)
+		fmt.Printf("\nRun:\n")
+		fmt.Printf(" brew upgrade && brew cleanup\n")
+	}
+	if len(g.Upgrade) > 0 {
+		fmt.Printf("△ - Updates available, upgrading: (%d packages%s)\n", len(g.Upgrade), majors(g.Upgrade))
+		showOutdated(g.Upgrade)
+	}
+	if len(g.Warn) > 0 {
+		fmt.Printf("△ - Updates available, reconciling anyway: (%d packages%s)\n", len(g.Warn), majors(g.Warn))
+		showOutdated(g.Warn)
+	}
+	if len(g.Held) > 0 {
+		fmt.Printf("△ - Updates held back: (%d packages%s)\n", len(g.Held), majors(g.Held))
+		showOutdated(g.Held)
+	}
+	if len(g.Unrelated) > 0 {
+		fmt.Printf("△ - Updates available for packages the config does not require: (%d packages%s)\n", len(g.Unrelated), majors(g.Unrelated))
+		showOutdated(g.Unrelated)
+	}
+	return len(g.Abort) > 0
+}
+
+// reportIndexAge shows how fresh the formula index the outdated packages come from is
+func reportIndexAge(updated, now time.Time) {
+	age := now.Sub(updated)
+	switch {
+	case updated.IsZero():
+		fmt.Printf("△ - Formula index: last update unknown (brew update skipped)\n")
+	case age >= staleIndex:
+		fmt.Printf("△ - Formula index updated %s ago: outdated packages may be missing (brew update skipped)\n", cache.FormatAge(age))
+	default:
+		fmt.Printf("✓ - Formula index updated %s ago\n", cache.FormatAge(age))
+	}
+}
+
+// majors counts the major upgrades, for a header: ", 2 major"
+func majors(pkgs []actual.OutdatedPackage) string {
+	if n := len(actual.Majors(pkgs)); n > 0 {
+		return fmt.Sprintf(", %d major", n)
+	}
+	return ""
+}
+
+// showOutdated lists upgrades, with major ones highlighted:
+//
+//   - wget: 1.24.5 -> 1.25.0 (minor)
+//   - node: 22.11.0 -> 23.3.0 (MAJOR)
+//   - google-chrome: 131.0.6778.86 -> latest (unknown)
+func showOutdated(pkgs []actual.OutdatedPackage) {
+	for _, p := range pkgs {
+		bump := string(p.Bump)
+		if p.Bump == actual.BumpMajor {
+			bump = strings.ToUpper(bump)
+		}
+		if p.Pinned {
+			bump += ", pinned"
+		}
+		fmt.Printf(" - %s: %s -> %s (%s)\n", p.Name, cmp.Or(p.Installed, "unknown"), cmp.Or(p.Current, "unknown"), bump)
+	}
+}
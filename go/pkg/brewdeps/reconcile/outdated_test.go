@@ -0,0 +1,67 @@
+package reconcile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/actual"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+	"github.com/daneroo/dotfiles/go/pkg/config"
+)
+
+func TestGateOutdated(t *testing.T) {
+	p := func(name string) types.Package { return types.Package{Name: name} }
+	// wget -> openssl@3, go, postgresql@16 and node are configured, ffmpeg is extraneous
+	state := types.ActualState{
+		Packages: []types.Package{p("wget"), p("openssl@3"), p("go"), p("postgresql@16"), p("node"), p("ffmpeg")},
+		DepsMap:  map[types.Package][]types.Package{p("wget"): {p("openssl@3")}},
+		Info: map[types.Package]types.PackageInfo{
+			p("node"): {Pinned: true},
+		},
+	}
+	desired := []types.Package{p("wget"), p("go"), p("postgresql@16"), p("node")}
+	outdated := func(names ...string) actual.OutdatedPackages {
+		var o actual.OutdatedPackages
+		for _, name := range names {
+			o.Formulae = append(o.Formulae, actual.OutdatedPackage{Package: p(name), Pinned: state.Info[p(name)].Pinned})
+		}
+		return o
+	}
+	names := func(pkgs []actual.OutdatedPackage) []string {
+		var names []string
+		for _, p := range pkgs {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+	policy := config.BrewOutdated{
+		Policy:   config.OutdatedWarn,
+		Packages: map[string]string{"go": config.OutdatedUpgrade, "postgresql@16": config.OutdatedHold, "openssl@3": config.OutdatedAbort},
+	}
+
+	gate := GateOutdated(outdated("ffmpeg", "go", "node", "openssl@3", "postgresql@16", "wget"), desired, policy, state)
+	for _, tt := range []struct {
+		name string
+		got  []actual.OutdatedPackage
+		want []string
+	}{
+		{"Abort", gate.Abort, []string{"openssl@3"}},
+		{"Warn", gate.Warn, []string{"wget"}},
+		{"Upgrade", gate.Upgrade, []string{"go"}},
+		{"Held", gate.Held, []string{"node", "postgresql@16"}},
+		{"Unrelated", gate.Unrelated, []string{"ffmpeg"}},
+	} {
+		if got := names(tt.got); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if want := []types.Package{p("postgresql@16")}; !reflect.DeepEqual(gate.Unpinned, want) {
+		t.Errorf("Unpinned = %v, want %v", gate.Unpinned, want)
+	}
+
+	// only held and unrelated packages are outdated: reconcile anyway, even with the default policy
+	policy = config.BrewOutdated{Packages: map[string]string{"postgresql@16": config.OutdatedHold}}
+	if gate := GateOutdated(outdated("ffmpeg", "node", "postgresql@16"), desired, policy, state); len(gate.Abort) > 0 {
+		t.Errorf("Abort = %v, want none", names(gate.Abort))
+	}
+}
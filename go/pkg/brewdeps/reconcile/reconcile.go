@@ -4,29 +4,32 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/daneroo/dotfiles/go/pkg/brewdeps/actual"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/graph"
 	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
 )
 
-// Reconcile performs a complete reconciliation cycle.
+// Reconcile performs a complete reconciliation cycle against the actual state
+// (see actual.GetActual, collected by the caller), with the desired packages named
+// like the installed ones (see Canonicalize).
 // Currently it only shows the actions needed, but will eventually:
 // 1. Return structured actions that can be executed
 // 2. Support --dry-run vs execute modes
 // 3. Handle errors during execution
-func Reconcile(desired []types.Package) error {
-	// Get actual state
-	actualState, err := actual.GetActual()
-	if err != nil {
-		return err
-	}
+func Reconcile(desired []types.Package, actualState types.ActualState) error {
 	fmt.Printf("✓ - Dependency map is consistent\n")
 
+	reportStatus(actualState)
+
+	desired, renames := Canonicalize(desired, actualState)
+	reportRenames(renames)
 	missing := CheckMissing(desired, actualState.Packages)
-	extra := Extraneous(desired, actualState.Packages, actualState.DepsMap)
+	g := graph.New(actualState.Packages, actualState.DepsMap)
+	reportCycles(g)
+	plan := PlanUninstall(desired, actualState.Packages, g)
 
 	// TODO: This will evolve into proper Actions that can be shown/executed
 	showActions(missing, installAction)
-	showActions(extra, uninstallAction)
+	showUninstallPlan(plan)
 
 	return nil
 }
@@ -34,6 +37,30 @@ func Reconcile(desired []types.Package) error {
 // Internal implementation details below
 // ===================================
 
+// reportCycles warns about dependency cycles, which brew is not supposed to have
+func reportCycles(g *graph.Graph) {
+	for _, cycle := range g.Cycles() {
+		var names []string
+		for _, pkg := range cycle {
+			names = append(names, pkg.Name)
+		}
+		fmt.Printf("△ - Dependency cycle between %s\n", strings.Join(names, ", "))
+	}
+}
+
+// reportStatus warns about installed packages that are deprecated or disabled in their tap
+func reportStatus(actualState types.ActualState) {
+	for _, pkg := range actualState.Packages {
+		info := actualState.Info[pkg]
+		switch {
+		case info.Disabled:
+			fmt.Printf("△ - %s is disabled in %s: it can no longer be installed or upgraded\n", pkg.Name, info.Tap)
+		case info.Deprecated:
+			fmt.Printf("△ - %s is deprecated in %s: it will be disabled\n", pkg.Name, info.Tap)
+		}
+	}
+}
+
 // actionType combines the verb (install/uninstall) with its state description (Missing/Extraneous)
 // This allows us to handle both installation and removal with the same code path,
 // while maintaining clear output messages.
@@ -53,6 +80,44 @@ var (
 	}
 )
 
+// showUninstallPlan shows the commands removing every extraneous package,
+// dependents before their dependencies, previewing the dependencies orphaned by the roots.
+//
+//	✗ - Extraneous casks/formulae: (3 packages, 1 root)
+//	 Orphaned once the roots are removed:
+//	 - x264 (only needed by ffmpeg)
+//	 - lame (only needed by ffmpeg)
+//	 brew uninstall --formula ffmpeg
+//	 brew uninstall --formula x264
+//	 brew uninstall --formula lame
+//
+//	  or all together:
+//
+//	 brew uninstall --formula ffmpeg x264 lame
+func showUninstallPlan(plan UninstallPlan) {
+	if len(plan.Order) == 0 {
+		fmt.Printf("✓ - No %s casks/formulae\n", strings.ToLower(uninstallAction.state))
+		return
+	}
+	fmt.Printf("✗ - %s casks/formulae: (%d packages, %d roots)\n", uninstallAction.state, len(plan.Order), len(plan.Roots))
+	if len(plan.Orphans) > 0 {
+		fmt.Printf(" Orphaned once the roots are removed:\n")
+		for _, pkg := range plan.Orphans {
+			var roots []string
+			for _, root := range plan.HeldBy[pkg] {
+				roots = append(roots, root.Name)
+			}
+			fmt.Printf(" - %s (only needed by %s)\n", pkg.Name, strings.Join(roots, ", "))
+		}
+	}
+	// Individual commands, in order: casks depend on formulae, never the reverse
+	showCommands(plan.Order, uninstallAction.verb, commandOptions{isCask: true, groupCommand: false})
+	showCommands(plan.Order, uninstallAction.verb, commandOptions{isCask: false, groupCommand: false})
+	fmt.Printf("\n  or all together:\n")
+	showCommands(plan.Order, uninstallAction.verb, commandOptions{isCask: true, groupCommand: true})
+	showCommands(plan.Order, uninstallAction.verb, commandOptions{isCask: false, groupCommand: true})
+}
+
 // commandOptions configures how commands are displayed
 type commandOptions struct {
 	isCask       bool
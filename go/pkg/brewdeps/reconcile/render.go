@@ -0,0 +1,31 @@
+package reconcile
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/graph"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+// RenderGraph writes the dependency graph of the installed packages (see graph.Graph.Render),
+// colored by kind: configured, dependency or extraneous.
+// When around is set, only the packages it depends on, and those depending on it, are rendered.
+func RenderGraph(w io.Writer, format, around string, required []types.Package, actualState types.ActualState) error {
+	g := graph.New(actualState.Packages, actualState.DepsMap)
+	var only graph.Set
+	if around != "" {
+		matches := findPackages(around, actualState)
+		if len(matches) == 0 {
+			return fmt.Errorf("%s is not installed", around)
+		}
+		only = make(graph.Set)
+		for _, pkg := range matches {
+			for p := range g.Around(pkg) {
+				only[p] = true
+			}
+		}
+	}
+	required, _ = Canonicalize(required, actualState)
+	return g.Render(w, format, g.Classify(required), only)
+}
@@ -0,0 +1,132 @@
+package reconcile
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/graph"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+)
+
+// maxWhyPaths is how many dependency paths Why shows per configured package
+const maxWhyPaths = 5
+
+// Why explains why a package is installed (see `checkdeps why <package>`):
+//   - every dependency path from the configured packages (with their config section) to it
+//   - or, when no configured package requires it, the extraneous packages it is installed under
+//
+// name matches a package by its full name ("teamookla/speedtest/speedtest"), its basename ("speedtest"),
+// or any other name brew knows it by (see types.ActualState.Canonical).
+// sections maps the configured packages to their config section (see config.Config.BrewSections).
+func Why(name string, required []types.Package, sections map[types.Package]string, actualState types.ActualState) error {
+	matches := findPackages(name, actualState)
+	if len(matches) == 0 {
+		for _, pkg := range required {
+			if pkg.Name == name || path.Base(pkg.Name) == name {
+				fmt.Printf("✗ - %s is configured (%s), but not installed\n", pkg.Name, sections[pkg])
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not installed", name)
+	}
+
+	required, _ = Canonicalize(required, actualState)
+	sections = canonicalSections(sections, actualState)
+	g := graph.New(actualState.Packages, actualState.DepsMap)
+	closure := g.Closure(required)
+	for _, pkg := range matches {
+		if closure[pkg] {
+			explainRequired(pkg, required, sections, g)
+		} else {
+			explainExtraneous(pkg, required, actualState.Packages, g)
+		}
+	}
+	return nil
+}
+
+// explainRequired shows the dependency paths from the configured packages to pkg
+func explainRequired(pkg types.Package, required []types.Package, sections map[types.Package]string, g *graph.Graph) {
+	fmt.Printf("✓ - %s is required\n", describe(pkg))
+	roots := slices.Clone(required)
+	sort.SliceStable(roots, func(i, j int) bool {
+		if sections[roots[i]] != sections[roots[j]] {
+			return sections[roots[i]] < sections[roots[j]]
+		}
+		return roots[i].Name < roots[j].Name
+	})
+	for _, root := range roots {
+		if root == pkg {
+			fmt.Printf(" %s: %s (configured)\n", sections[root], root.Name)
+			continue
+		}
+		paths := g.Paths(root, pkg, maxWhyPaths+1)
+		for i, p := range paths {
+			if i == maxWhyPaths {
+				fmt.Printf(" %s: ... more paths from %s\n", sections[root], root.Name)
+				break
+			}
+			fmt.Printf(" %s: %s\n", sections[root], formatPath(p))
+		}
+	}
+}
+
+// explainExtraneous shows the extraneous packages pkg is installed under, see Extraneous
+func explainExtraneous(pkg types.Package, required, installed []types.Package, g *graph.Graph) {
+	fmt.Printf("✗ - %s is extraneous: no configured package requires it\n", describe(pkg))
+	for _, root := range Extraneous(required, installed, g) {
+		for _, p := range g.Paths(root, pkg, 1) {
+			if root == pkg {
+				fmt.Printf(" it is an extraneous root itself\n")
+			} else {
+				fmt.Printf(" under the extraneous root %s: %s\n", root.Name, formatPath(p))
+			}
+			fmt.Printf(" brew uninstall %s %s\n", kindFlag(root), root.Name)
+		}
+	}
+}
+
+// findPackages returns the installed packages named name, by full name or basename
+// (from any tap), or else the formula or cask brew resolves it to
+func findPackages(name string, actualState types.ActualState) []types.Package {
+	var matches []types.Package
+	for _, pkg := range actualState.Packages {
+		if pkg.Name == name || path.Base(pkg.Name) == name {
+			matches = append(matches, pkg)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+	for _, pkg := range []types.Package{{Name: name}, {Name: name, IsCask: true}} {
+		if canonical, match := actualState.Canonical(pkg); match != types.NotInstalled {
+			matches = append(matches, canonical)
+		}
+	}
+	return matches
+}
+
+func describe(pkg types.Package) string {
+	if pkg.IsCask {
+		return pkg.Name + " (cask)"
+	}
+	return pkg.Name
+}
+
+func kindFlag(pkg types.Package) string {
+	if pkg.IsCask {
+		return "--cask"
+	}
+	return "--formula"
+}
+
+// formatPath shows a dependency path: "ffmpeg -> x264 -> openssl@3"
+func formatPath(p []types.Package) string {
+	var names []string
+	for _, pkg := range p {
+		names = append(names, pkg.Name)
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,86 @@
+package types
+
+import (
+	"path"
+	"slices"
+)
+
+// Match tells how a name resolved to an installed package (see ActualState.Canonical)
+type Match int
+
+const (
+	// NotInstalled: no installed package is known by that name
+	NotInstalled Match = iota
+	// Exact: the name is the package's full name, e.g. "teamookla/speedtest/speedtest", "wget"
+	Exact
+	// ShortName: the name is the package's name without its tap, or qualified by a core tap,
+	// e.g. "speedtest" for "teamookla/speedtest/speedtest", "homebrew/core/wget" for "wget"
+	ShortName
+	// Alias: the name is an alias of the package, e.g. "python3" for "python@3.13"
+	Alias
+	// OldName: the package was renamed from that name, e.g. "ipfs" to "kubo"
+	OldName
+)
+
+func (m Match) String() string {
+	switch m {
+	case Exact:
+		return "exact"
+	case ShortName:
+		return "short name"
+	case Alias:
+		return "alias"
+	case OldName:
+		return "old name"
+	}
+	return "not installed"
+}
+
+// Canonical resolves a name to the installed package it designates, of the same kind:
+// by its full name, its name without tap (when no other tap has it), an alias or an old name.
+// Brew accepts every one of these, so must the comparisons between config, dependencies and
+// installed packages.
+func (s ActualState) Canonical(pkg Package) (Package, Match) {
+	if _, ok := s.Info[pkg]; ok {
+		return pkg, Exact
+	}
+	best, match, ambiguous := Package{}, NotInstalled, false
+	for _, inst := range s.Packages {
+		if inst.IsCask != pkg.IsCask {
+			continue
+		}
+		m := s.Info[inst].matches(inst, pkg.Name)
+		switch {
+		case m == NotInstalled:
+		case match == NotInstalled || m < match:
+			best, match, ambiguous = inst, m, false
+		case m == match && inst != best:
+			ambiguous = true
+		}
+	}
+	if ambiguous {
+		// e.g. two taps with a formula of that name: brew would not guess either
+		return pkg, NotInstalled
+	}
+	return best, match
+}
+
+// matches tells how name designates an installed package, other than by its full name
+func (info PackageInfo) matches(inst Package, name string) Match {
+	base := path.Base(inst.Name)
+	qualify := func(n string) string {
+		if info.Tap == "" {
+			return n
+		}
+		return info.Tap + "/" + n
+	}
+	switch {
+	case name == base, name == qualify(base):
+		return ShortName
+	case slices.Contains(info.Aliases, name), slices.Contains(info.Aliases, path.Base(name)) && name == qualify(path.Base(name)):
+		return Alias
+	case slices.Contains(info.OldNames, name), slices.Contains(info.OldNames, path.Base(name)) && name == qualify(path.Base(name)):
+		return OldName
+	}
+	return NotInstalled
+}
@@ -9,4 +9,46 @@ type Package = config.BrewPackage
 type ActualState struct {
 	Packages []Package
 	DepsMap  map[Package][]Package // For determining transitive dependencies
+	// Info holds what brew knows about every installed package
+	Info map[Package]PackageInfo
+}
+
+// PackageInfo describes an installed formula or cask (from brew info --json=v2 --installed)
+type PackageInfo struct {
+	// Version is the installed version (the most recent, when several are installed side by side)
+	Version string
+	// CurrentVersion is the version brew would upgrade to
+	CurrentVersion string
+	// Tap is the tap the package comes from, e.g. "homebrew/core", "teamookla/speedtest"
+	Tap string
+	// Aliases are the other names of the package, e.g. "python3" for "python@3.13"
+	Aliases []string
+	// OldNames are the names the package was renamed from, e.g. "ipfs" for "kubo"
+	OldNames []string
+	// OnRequest is false for formulae installed only as a dependency of another package
+	OnRequest bool
+	// Pinned formulae are not upgraded by brew upgrade (see brew pin)
+	Pinned bool
+	// Outdated is true when CurrentVersion is newer than Version
+	Outdated bool
+	// Deprecated and Disabled packages are (or will soon be) removed from their tap
+	Deprecated bool
+	Disabled   bool
+	// RuntimeDeps are the direct runtime dependencies, BuildDeps the build-time ones
+	RuntimeDeps []Package
+	BuildDeps   []Package
+	// Caveats are the post-install notes of the package, if any
+	Caveats string
+}
+
+// Versions returns the installed version of the given packages, keyed by the given names,
+// which are resolved like brew does (see Canonical). Packages that are not installed are left out.
+func (s ActualState) Versions(pkgs []Package) map[Package]string {
+	versions := make(map[Package]string, len(pkgs))
+	for _, pkg := range pkgs {
+		if canonical, match := s.Canonical(pkg); match != NotInstalled {
+			versions[pkg] = s.Info[canonical].Version
+		}
+	}
+	return versions
 }
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is a cached blob along with the metadata needed to revalidate it.
+type Entry struct {
+	Data      []byte    `json:"-"`
+	ETag      string    `json:"etag,omitempty"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// Age returns how long ago the entry was fetched.
+func (e Entry) Age() time.Duration {
+	return time.Since(e.FetchedAt)
+}
+
+// Store keeps entries as files in a directory:
+//   - <dir>/<key>           the data
+//   - <dir>/<key>.meta.json the Entry metadata (etag, fetchedAt)
+//
+// Keys may contain "/" to group entries in subdirectories (e.g. "asdf/python").
+type Store struct {
+	Dir string
+}
+
+// DefaultDir returns the checkdeps cache directory: $XDG_CACHE_HOME/checkdeps,
+// or ~/.cache/checkdeps when XDG_CACHE_HOME is not set.
+// We deliberately do not use os.UserCacheDir, which is ~/Library/Caches on macOS.
+func DefaultDir() (string, error) {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "checkdeps"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding cache directory: %w", err)
+	}
+	return filepath.Join(home, ".cache", "checkdeps"), nil
+}
+
+// NewDefaultStore returns a Store rooted at DefaultDir.
+func NewDefaultStore() (Store, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return Store{}, err
+	}
+	return Store{Dir: dir}, nil
+}
+
+// ErrNotCached is returned by Read when there is no entry for the key.
+var ErrNotCached = errors.New("not cached")
+
+// Read returns the cached entry for key, or ErrNotCached.
+func (s Store) Read(key string) (Entry, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return Entry{}, ErrNotCached
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("reading cache %s: %w", key, err)
+	}
+
+	var entry Entry
+	meta, err := os.ReadFile(s.path(key) + ".meta.json")
+	if err == nil {
+		if err := json.Unmarshal(meta, &entry); err != nil {
+			return Entry{}, fmt.Errorf("parsing cache metadata %s: %w", key, err)
+		}
+	} else if info, statErr := os.Stat(s.path(key)); statErr == nil {
+		// metadata missing: fall back to the file's modification time
+		entry.FetchedAt = info.ModTime()
+	}
+	entry.Data = data
+	return entry, nil
+}
+
+// Write stores the entry for key, creating directories as needed.
+func (s Store) Write(key string, entry Entry) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := writeFile(p, entry.Data); err != nil {
+		return fmt.Errorf("writing cache %s: %w", key, err)
+	}
+	return s.writeMeta(key, entry)
+}
+
+// writeFile replaces a file atomically: the data is written to a temporary file
+// in the same directory, then renamed over it, so that an interrupted run never
+// leaves a truncated entry behind
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // once renamed, there is nothing left to remove
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Touch marks an entry as freshly fetched without rewriting its data,
+// e.g. after the server answered 304 Not Modified.
+func (s Store) Touch(key string, entry Entry) error {
+	entry.FetchedAt = time.Now()
+	return s.writeMeta(key, entry)
+}
+
+func (s Store) writeMeta(key string, entry Entry) error {
+	meta, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(s.path(key)+".meta.json", meta); err != nil {
+		return fmt.Errorf("writing cache metadata %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s Store) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+// FormatAge formats a duration coarsely for humans: "45m", "5h", "3d4h".
+func FormatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		days := int(d.Hours()) / 24
+		return fmt.Sprintf("%dd%dh", days, int(d.Hours())-24*days)
+	}
+}
@@ -0,0 +1,65 @@
+// Package collect runs read-only state queries (asdf list, npm ls, git fetch, ...)
+// concurrently, with a bounded number of workers.
+//
+// Only queries go through Run: mutations (installs, uninstalls, reshims) stay
+// sequential in the callers. Steps should not print: their results are reported
+// by the caller once Run returns, so that output keeps a stable order.
+package collect
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/config"
+)
+
+// Workers is how many steps run at the same time
+const Workers = 4
+
+// Step is a named query; Run stores its result, typically in a variable of the caller
+type Step struct {
+	Name string
+	Run  func() error
+}
+
+// Run runs the steps, at most workers at a time, and waits for all of them.
+// The errors of failed steps are joined, in step order.
+// In verbose mode, the duration of every step is shown, in step order.
+func Run(workers int, steps ...Step) error {
+	if workers < 1 {
+		workers = 1
+	}
+	errs := make([]error, len(steps))
+	durations := make([]time.Duration, len(steps))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, step := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			t := time.Now()
+			if err := step.Run(); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", step.Name, err)
+			}
+			durations[i] = time.Since(t)
+		}()
+	}
+	wg.Wait()
+
+	if config.Global.Verbose && len(steps) > 0 {
+		fmt.Printf("Timing: (%d steps, %d workers, %s total)\n", len(steps), workers, round(time.Since(start)))
+		for i, step := range steps {
+			fmt.Printf(" - %s: %s\n", step.Name, round(durations[i]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
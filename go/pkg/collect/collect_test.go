@@ -0,0 +1,48 @@
+package collect
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var running, peak atomic.Int32
+	var steps []Step
+	for i := 0; i < 8; i++ {
+		steps = append(steps, Step{Name: "step", Run: func() error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		}})
+	}
+	if err := Run(3, steps...); err != nil {
+		t.Fatal(err)
+	}
+	if got := peak.Load(); got > 3 || got < 1 {
+		t.Errorf("peak concurrency = %d, want 1..3", got)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	errFirst := errors.New("first")
+	err := Run(2,
+		Step{Name: "a", Run: func() error { time.Sleep(10 * time.Millisecond); return errFirst }},
+		Step{Name: "b", Run: func() error { return nil }},
+		Step{Name: "c", Run: func() error { return errors.New("second") }},
+	)
+	if !errors.Is(err, errFirst) {
+		t.Fatalf("Run() = %v, want it to wrap %v", err, errFirst)
+	}
+	if want := "a: first\nc: second"; err.Error() != want {
+		t.Errorf("Run() = %q, want %q", err, want)
+	}
+}
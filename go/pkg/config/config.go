@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -28,9 +29,56 @@ type packageConfig struct {
 	Homebrew struct {
 		FormulaeBySection map[string][]string `yaml:"formulae"`
 		Casks             []string            `yaml:"casks"`
+		Outdated          brewOutdatedConfig  `yaml:"outdated"`
 	} `yaml:"homebrew"`
-	Asdf map[string][]string `yaml:"asdf"`
-	Npm  []string            `yaml:"npm"`
+	Asdf           map[string]asdfPluginConfig `yaml:"asdf"`
+	RuntimeManager string                      `yaml:"runtime_manager"`
+	Npm            []string                    `yaml:"npm"`
+	Workspace      struct {
+		Roots []string `yaml:"roots"`
+	} `yaml:"workspace"`
+	Requires map[string][]string `yaml:"requires"`
+}
+
+// brewOutdatedConfig is the outdated policy of the homebrew section:
+//
+//	outdated:
+//	  policy: warn
+//	  packages:
+//	    postgresql@16: hold
+//	    go: upgrade
+type brewOutdatedConfig struct {
+	Policy   string            `yaml:"policy"`
+	Packages map[string]string `yaml:"packages"`
+}
+
+// asdfPluginConfig is one plugin entry of the asdf section, either a list of specs:
+//
+//	python: ["3.12", "3.11"]
+//
+// or a mapping with specs and per-plugin settings:
+//
+//	python:
+//	  versions: ["3.12", "3.11"]
+//	  keep_previous: 1
+//	  keep_if_referenced_by: ["~/Code"]
+//	  url: https://github.com/asdf-community/asdf-python.git
+//	  ref: v1.2.0
+type asdfPluginConfig struct {
+	Versions           []string `yaml:"versions"`
+	KeepPrevious       int      `yaml:"keep_previous"`
+	KeepIfReferencedBy []string `yaml:"keep_if_referenced_by"`
+	URL                string   `yaml:"url"`
+	Ref                string   `yaml:"ref"`
+}
+
+// UnmarshalYAML accepts both the list and the mapping form of a plugin entry
+func (p *asdfPluginConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&p.Versions)
+	}
+	type plain asdfPluginConfig // avoid recursing into this method
+	return node.Decode((*plain)(p))
 }
 
 // LoadConfig loads and validates the configuration from the specified file
@@ -52,17 +100,51 @@ func LoadConfig(configFile string) (*Config, error) {
 	// After validation passes, flatten into final Config
 	var cfg Config
 	cfg.Npm = temp.Npm
-	cfg.Asdf = temp.Asdf
+	cfg.Workspace = temp.Workspace.Roots
+	cfg.RuntimeManager = temp.RuntimeManager
+	cfg.Requires = temp.Requires
+	if cfg.RuntimeManager == "" {
+		cfg.RuntimeManager = "asdf"
+	}
+	cfg.Asdf = make(map[string][]string)
+	cfg.AsdfRetention = make(map[string]AsdfRetention)
+	cfg.AsdfSources = make(map[string]AsdfPluginSource)
+	for plugin, p := range temp.Asdf {
+		cfg.Asdf[plugin] = p.Versions
+		if p.URL != "" || p.Ref != "" {
+			cfg.AsdfSources[plugin] = AsdfPluginSource{URL: p.URL, Ref: p.Ref}
+		}
+		if p.KeepPrevious > 0 || len(p.KeepIfReferencedBy) > 0 {
+			cfg.AsdfRetention[plugin] = AsdfRetention{
+				KeepPrevious:       p.KeepPrevious,
+				KeepIfReferencedBy: p.KeepIfReferencedBy,
+			}
+		}
+	}
+
+	cfg.BrewOutdated = BrewOutdated{
+		Policy:   temp.Homebrew.Outdated.Policy,
+		Packages: temp.Homebrew.Outdated.Packages,
+	}
+	if cfg.BrewOutdated.Policy == "" {
+		cfg.BrewOutdated.Policy = OutdatedAbort
+	}
 
 	// Convert and flatten formulae sections and casks into []Package
+	// remembering the section of each one
 	cfg.Homebrew = make([]BrewPackage, 0)
-	for _, formulae := range temp.Homebrew.FormulaeBySection {
+	cfg.BrewSections = make(map[BrewPackage]string)
+	for section, formulae := range temp.Homebrew.FormulaeBySection {
 		for _, f := range formulae {
-			cfg.Homebrew = append(cfg.Homebrew, BrewPackage{Name: f, IsCask: false})
+			pkg := BrewPackage{Name: f, IsCask: false}
+			cfg.Homebrew = append(cfg.Homebrew, pkg)
+			cfg.BrewSections[pkg] = "formulae." + section
 		}
 	}
 	for _, c := range temp.Homebrew.Casks {
-		cfg.Homebrew = append(cfg.Homebrew, BrewPackage{Name: c, IsCask: true})
+		pkg := BrewPackage{Name: c, IsCask: true}
+		cfg.Homebrew = append(cfg.Homebrew, pkg)
+		cfg.BrewSections[pkg] = "casks"
 	}
 
 	fmt.Printf("✓ - Configuration loaded\n")
@@ -114,6 +196,20 @@ func validateConfig(cfg *packageConfig) error {
 		}
 	}
 
+	// Validate outdated policies
+	switch p := cfg.Homebrew.Outdated.Policy; p {
+	case "", OutdatedAbort, OutdatedWarn, OutdatedUpgrade:
+	default:
+		violations = append(violations, fmt.Sprintf("✗ - homebrew.outdated.policy %q must be abort, warn or upgrade", p))
+	}
+	for pkg, p := range cfg.Homebrew.Outdated.Packages {
+		switch p {
+		case OutdatedAbort, OutdatedWarn, OutdatedUpgrade, OutdatedHold:
+		default:
+			violations = append(violations, fmt.Sprintf("✗ - homebrew.outdated.packages %q: policy %q must be abort, warn, upgrade or hold", pkg, p))
+		}
+	}
+
 	// Validate npm packages are sorted
 	if sortViolations := validateSorting(cfg.Npm); len(sortViolations) > 0 {
 		violations = append(violations, "✗ - NPM packages are not sorted")
@@ -123,12 +219,24 @@ func validateConfig(cfg *packageConfig) error {
 	}
 
 	// Validate asdf plugin versions
-	for plugin, versions := range cfg.Asdf {
-		for _, version := range versions {
+	for plugin, p := range cfg.Asdf {
+		for _, version := range p.Versions {
 			if err := validateAsdfVersion(version, plugin); err != nil {
 				violations = append(violations, fmt.Sprintf("  ✗ - Plugin %q: %v", plugin, err))
 			}
 		}
+		if p.KeepPrevious < 0 {
+			violations = append(violations, fmt.Sprintf("  ✗ - Plugin %q: keep_previous must not be negative", plugin))
+		}
+		if strings.HasPrefix(p.Ref, "-") || strings.ContainsAny(p.Ref, " \t") {
+			violations = append(violations, fmt.Sprintf("  ✗ - Plugin %q: invalid ref %q", plugin, p.Ref))
+		}
+	}
+
+	violations = append(violations, validateRequires(cfg)...)
+
+	if m := cfg.RuntimeManager; m != "" && m != "asdf" && m != "mise" {
+		violations = append(violations, fmt.Sprintf("✗ - runtime_manager %q must be asdf or mise", m))
 	}
 
 	if len(violations) > 0 {
@@ -172,32 +280,95 @@ func compareByBasename(i, j string) bool {
 	return iBase < jBase
 }
 
+// validateRequires checks that requires keys name a section ("asdf") or a package
+// ("asdf:python"), and that every prerequisite ("brew:openssl@3") is in the config
+func validateRequires(cfg *packageConfig) []string {
+	configured := map[string]bool{}
+	for _, formulae := range cfg.Homebrew.FormulaeBySection {
+		for _, f := range formulae {
+			configured["brew:"+f] = true
+			configured["brew:"+path.Base(f)] = true
+		}
+	}
+	for _, c := range cfg.Homebrew.Casks {
+		configured["brew:"+c] = true
+		configured["brew:"+path.Base(c)] = true
+	}
+	for plugin := range cfg.Asdf {
+		configured["asdf:"+plugin] = true
+	}
+	for _, pkg := range cfg.Npm {
+		configured["npm:"+pkg] = true
+	}
+
+	var violations []string
+	for key, prerequisites := range cfg.Requires {
+		section, _, isPackage := strings.Cut(key, ":")
+		if !slices.Contains(Sections, section) || (isPackage && !configured[key]) {
+			violations = append(violations, fmt.Sprintf("✗ - requires: %q is not a section (%s) nor a configured package", key, strings.Join(Sections, ", ")))
+			continue
+		}
+		for _, p := range prerequisites {
+			manager, _, _ := strings.Cut(p, ":")
+			switch {
+			case !configured[p]:
+				violations = append(violations, fmt.Sprintf("✗ - requires: %q needs %q, which is not in the %s section", key, p, manager))
+			case manager == section:
+				violations = append(violations, fmt.Sprintf("✗ - requires: %q needs %q, from its own section", key, p))
+			}
+		}
+	}
+	return violations
+}
+
+var (
+	// ltsCodenamePattern matches a nodejs LTS line, e.g. "lts/jod"
+	ltsCodenamePattern = regexp.MustCompile(`^lts/[a-z]+$`)
+	// asdfVersionPattern matches a version prefix: "X[.Y[.Z]]"
+	asdfVersionPattern = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+)
+
 // validateAsdfVersion validates version format for asdf plugins
 // Supported formats:
 // - "latest": resolves to the latest stable version (using asdf latest <plugin>)
 // - "lts": for nodejs only, resolves to the latest LTS version from nodejs.org
+// - "lts/<codename>": for nodejs only, the latest version of an LTS line (e.g. "lts/jod")
 // - "X[.Y[.Z]]": resolves to the latest version matching the prefix:
 //   - "3" -> latest 3.x.x
 //   - "3.12" -> latest 3.12.x
 //   - "3.12.0" -> exact version
+//
+// - "system": the runtime installed outside asdf (e.g. by brew)
+// - "path:<dir>": a runtime built locally in an absolute directory
+// - "ref:<git-ref>": a runtime built by asdf from a git ref
 func validateAsdfVersion(version string, plugin string) error {
-	if version == "latest" {
+	if version == "latest" || version == "system" {
+		return nil
+	}
+	if dir, ok := strings.CutPrefix(version, "path:"); ok {
+		if !path.IsAbs(dir) && !strings.HasPrefix(dir, "~/") {
+			return fmt.Errorf("invalid version %q: path must be absolute", version)
+		}
+		return nil
+	}
+	if ref, ok := strings.CutPrefix(version, "ref:"); ok {
+		if ref == "" || strings.ContainsAny(ref, " \t") {
+			return fmt.Errorf("invalid version %q: ref must be a git ref", version)
+		}
 		return nil
 	}
-	if version == "lts" {
+	if version == "lts" || ltsCodenamePattern.MatchString(version) {
 		if plugin != "nodejs" {
 			return fmt.Errorf("version %q is only valid for nodejs, not for %q", version, plugin)
 		}
 		return nil
 	}
 
-	pattern := `^\d+(\.\d+){0,2}$`
-	matched, _ := regexp.MatchString(pattern, version)
-	if !matched {
+	if !asdfVersionPattern.MatchString(version) {
 		if plugin == "nodejs" {
-			return fmt.Errorf("invalid version format %q: must be 'latest', 'lts', or 'X[.Y[.Z]]'", version)
+			return fmt.Errorf("invalid version format %q: must be 'latest', 'lts', 'lts/<codename>', 'X[.Y[.Z]]', 'system', 'path:<dir>' or 'ref:<git-ref>'", version)
 		}
-		return fmt.Errorf("invalid version format %q: must be 'latest' or 'X[.Y[.Z]]'", version)
+		return fmt.Errorf("invalid version format %q: must be 'latest', 'X[.Y[.Z]]', 'system', 'path:<dir>' or 'ref:<git-ref>'", version)
 	}
 	return nil
 }
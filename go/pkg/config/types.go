@@ -6,9 +6,72 @@ type BrewPackage struct {
 	IsCask bool
 }
 
+// Outdated package policies (see BrewOutdated)
+const (
+	// OutdatedAbort stops the reconciliation until outdated packages are upgraded
+	OutdatedAbort = "abort"
+	// OutdatedWarn reports outdated packages, and reconciles anyway
+	OutdatedWarn = "warn"
+	// OutdatedUpgrade upgrades outdated packages before reconciling
+	OutdatedUpgrade = "upgrade"
+	// OutdatedHold keeps a package at its installed version: it is pinned (brew pin),
+	// and never stops the reconciliation. It only applies to single packages.
+	OutdatedHold = "hold"
+)
+
+// BrewOutdated is what to do with outdated brew packages before reconciling
+type BrewOutdated struct {
+	// Policy applies to packages without their own: abort (the default), warn or upgrade
+	Policy string
+	// Packages holds the policy of single packages, by name, including hold
+	Packages map[string]string
+}
+
+// AsdfRetention is the policy deciding which extraneous versions of a plugin
+// are kept and which are uninstalled
+type AsdfRetention struct {
+	// KeepPrevious is how many versions older than each resolved version are kept,
+	// e.g. 1 keeps python 3.12.7 after 3.12 resolved to 3.12.8
+	KeepPrevious int
+	// KeepIfReferencedBy lists directories scanned for .tool-versions files:
+	// versions pinned by a project there are kept (e.g. "~/Code")
+	KeepIfReferencedBy []string
+}
+
+// AsdfPluginSource is where a plugin comes from
+type AsdfPluginSource struct {
+	// URL is the plugin's git repository; empty uses the asdf plugin index
+	URL string
+	// Ref pins the plugin to a branch, tag or commit: pinned plugins are
+	// checked out at Ref and never updated past it
+	Ref string
+}
+
+// Sections are the parts of the configuration reconciled in turn,
+// in their default order
+var Sections = []string{"brew", "asdf", "npm", "completions"}
+
 // Config represents the complete configuration for all package managers
 type Config struct {
 	Homebrew []BrewPackage
-	Asdf     map[string][]string
-	Npm      []string
+	// BrewSections maps brew packages to the config section listing them,
+	// e.g. "formulae.main" or "casks"
+	BrewSections map[BrewPackage]string
+	// BrewOutdated is the policy for outdated brew packages
+	BrewOutdated BrewOutdated
+	Asdf         map[string][]string
+	// AsdfRetention holds the retention policy of plugins that declare one.
+	// Plugins without a policy only get removal hints for extraneous versions
+	AsdfRetention map[string]AsdfRetention
+	// AsdfSources holds the plugin URL and git ref of plugins that declare one
+	AsdfSources map[string]AsdfPluginSource
+	// RuntimeManager installs the asdf section's versions: "asdf" or "mise"
+	RuntimeManager string
+	Npm            []string
+	// Workspace lists directories scanned for project version files
+	// (.tool-versions, .nvmrc, .python-version) whose pinned versions are installed
+	Workspace []string
+	// Requires maps a section ("asdf") or a package ("asdf:python") to the
+	// packages of other managers it needs, e.g. ["brew:openssl@3", "brew:readline"]
+	Requires map[string][]string
 }
@@ -0,0 +1,232 @@
+// Package eol tells which runtime versions are past, or nearing, their end-of-life.
+//
+// The dataset comes from https://endoflife.date: a snapshot is bundled with
+// the binary, and `checkdeps refresh-eol` caches a fresh copy in ~/.cache/checkdeps/eol/.
+package eol
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+	"github.com/daneroo/dotfiles/go/pkg/version"
+)
+
+const (
+	// DefaultBaseURL serves <product>.json, the release cycles of a product
+	DefaultBaseURL = "https://endoflife.date/api"
+	// WarnWithin is how close to its end-of-life a version gets a warning
+	WarnWithin = 180 * 24 * time.Hour
+)
+
+// Products are the endoflife.date products in the dataset
+var Products = []string{"go", "nodejs", "python"}
+
+// aliases maps asdf plugin and brew formula names to endoflife.date products
+var aliases = map[string]string{
+	"node":   "nodejs",
+	"golang": "go",
+}
+
+// endedLongAgo is the end-of-life of cycles that ended on an unknown date
+var endedLongAgo = time.Unix(0, 0)
+
+//go:embed data/*.json
+var bundled embed.FS
+
+// Cycle is a release cycle (major, or major.minor, line) of a product
+type Cycle struct {
+	Cycle       string    // e.g. "3.12", "22"
+	ReleaseDate time.Time // zero if unknown
+	EOL         time.Time // zero if no end-of-life date is announced yet
+}
+
+// cycleEntry is a cycle as served by endoflife.date
+type cycleEntry struct {
+	Cycle       string `json:"cycle"`
+	ReleaseDate string `json:"releaseDate"`
+	// EOL is a date like "2028-10-31", or a boolean (false: not announced)
+	EOL interface{} `json:"eol"`
+}
+
+// Dataset holds the release cycles of every product
+type Dataset struct {
+	cycles map[string][]Cycle
+	// Source describes where the data comes from, e.g. "bundled" or "cached 3d ago"
+	Source string
+}
+
+// Product returns the endoflife.date product for an asdf plugin or brew formula,
+// e.g. "nodejs", "node@22" -> "nodejs"; "python@3.12" -> "python", or "" if not tracked
+func Product(name string) string {
+	name, _, _ = strings.Cut(name, "@")
+	if p, ok := aliases[name]; ok {
+		name = p
+	}
+	for _, p := range Products {
+		if p == name {
+			return p
+		}
+	}
+	return ""
+}
+
+func cacheKey(product string) string {
+	return "eol/" + product + ".json"
+}
+
+// Load returns the dataset: for every product, the cached copy written by Refresh,
+// or the bundled snapshot when there is none
+func Load(store cache.Store) (*Dataset, error) {
+	d := &Dataset{cycles: make(map[string][]Cycle), Source: "bundled"}
+	var oldest time.Duration
+	cached := 0
+	for _, product := range Products {
+		data, err := bundled.ReadFile("data/" + product + ".json")
+		if err != nil {
+			return nil, err
+		}
+		entry, err := store.Read(cacheKey(product))
+		switch {
+		case err == nil:
+			data = entry.Data
+			cached++
+			oldest = max(oldest, entry.Age())
+		case !errors.Is(err, cache.ErrNotCached):
+			return nil, err
+		}
+		if d.cycles[product], err = parse(data); err != nil {
+			return nil, fmt.Errorf("parsing %s end-of-life data: %w", product, err)
+		}
+	}
+	if cached == len(Products) {
+		d.Source = fmt.Sprintf("cached %s ago", cache.FormatAge(oldest))
+	} else if cached > 0 {
+		d.Source = "bundled, partially cached"
+	}
+	return d, nil
+}
+
+// Refresh fetches the dataset of every product from endoflife.date into the cache
+func Refresh(store cache.Store, baseURL string, client *http.Client) error {
+	for _, product := range Products {
+		url := strings.TrimSuffix(baseURL, "/") + "/" + product + ".json"
+		resp, err := client.Get(url)
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", url, err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", url, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("fetching %s: %s", url, resp.Status)
+		}
+		cycles, err := parse(data)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", url, err)
+		}
+		if err := store.Write(cacheKey(product), cache.Entry{Data: data, FetchedAt: time.Now()}); err != nil {
+			return err
+		}
+		fmt.Printf("✓ - %s: %d release cycles\n", product, len(cycles))
+	}
+	return nil
+}
+
+func parse(data []byte) ([]Cycle, error) {
+	var entries []cycleEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	cycles := make([]Cycle, 0, len(entries))
+	for _, e := range entries {
+		c := Cycle{Cycle: e.Cycle}
+		c.ReleaseDate, _ = time.Parse("2006-01-02", e.ReleaseDate)
+		switch eol := e.EOL.(type) {
+		case string:
+			c.EOL, _ = time.Parse("2006-01-02", eol)
+		case bool:
+			if eol {
+				c.EOL = endedLongAgo
+			}
+		}
+		cycles = append(cycles, c)
+	}
+	return cycles, nil
+}
+
+// Lookup returns the cycle of a version: the most specific cycle it belongs to
+// ("3.12.8" is in cycle "3.12", "22.12.0" in cycle "22").
+//
+// The dataset only lists recent cycles: a version older than the oldest cycle of
+// its major line ("3.6.15", older than "3.7"), or of the product ("14.21.3", older
+// than "16"), is in a cycle that ended on an unknown date.
+func (d *Dataset) Lookup(product, v string) (Cycle, bool) {
+	parsed, err := version.Parse(v)
+	if err != nil {
+		return Cycle{}, false
+	}
+	var found Cycle
+	ok := false
+	for _, c := range d.cycles[product] {
+		if parsed.HasPrefix(c.Cycle) && (!ok || len(c.Cycle) > len(found.Cycle)) {
+			found, ok = c, true
+		}
+	}
+	if ok {
+		return found, true
+	}
+
+	var oldest, oldestOfMajor *Cycle
+	for i, c := range d.cycles[product] {
+		cv, err := version.Parse(c.Cycle)
+		if err != nil {
+			continue
+		}
+		if oldest == nil || version.Compare(c.Cycle, oldest.Cycle) < 0 {
+			oldest = &d.cycles[product][i]
+		}
+		if cv.Release[0] == parsed.Release[0] && (oldestOfMajor == nil || version.Compare(c.Cycle, oldestOfMajor.Cycle) < 0) {
+			oldestOfMajor = &d.cycles[product][i]
+		}
+	}
+	for _, c := range []*Cycle{oldestOfMajor, oldest} {
+		if c != nil && version.Compare(v, c.Cycle) < 0 {
+			segments := min(len(parsed.Release), strings.Count(c.Cycle, ".")+1)
+			var cycle []string
+			for _, n := range parsed.Release[:segments] {
+				cycle = append(cycle, strconv.Itoa(n))
+			}
+			return Cycle{Cycle: strings.Join(cycle, "."), EOL: endedLongAgo}, true
+		}
+	}
+	return Cycle{}, false
+}
+
+// Warning returns a warning when a version is past its end-of-life,
+// or reaches it within WarnWithin, or "" otherwise
+func (d *Dataset) Warning(product, v string, now time.Time) string {
+	c, ok := d.Lookup(product, v)
+	if !ok || c.EOL.IsZero() {
+		return ""
+	}
+	if c.EOL.Equal(endedLongAgo) {
+		return fmt.Sprintf("%s %s is past its end-of-life (%s %s ended long ago)", product, v, product, c.Cycle)
+	}
+	if now.After(c.EOL) {
+		return fmt.Sprintf("%s %s is past its end-of-life (%s %s ended %s)", product, v, product, c.Cycle, c.EOL.Format("2006-01-02"))
+	}
+	if left := c.EOL.Sub(now); left < WarnWithin {
+		return fmt.Sprintf("%s %s reaches its end-of-life on %s (in %d days)", product, v, c.EOL.Format("2006-01-02"), int(left.Hours()/24))
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package eol
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+)
+
+func TestWarning(t *testing.T) {
+	d, err := Load(cache.Store{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Source != "bundled" {
+		t.Errorf("Source = %q, want bundled", d.Source)
+	}
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		version string
+		want    string // substring, "" for no warning
+	}{
+		{"python", "3.12.8", ""},
+		{"python@3.10", "3.10.15", "reaches its end-of-life on 2026-10-31 (in 30 days)"},
+		{"python", "3.9.21", "past its end-of-life (python 3.9 ended 2025-10-31)"},
+		{"node", "20.18.1", "past its end-of-life"},
+		{"nodejs", "22.12.0", ""},
+		{"golang", "1.25.1", ""},                           // no date announced
+		{"python", "3.1.5", "(python 3.1 ended long ago)"}, // older than the oldest 3.x cycle, not in 3.10
+		{"python", "3.6.15", "(python 3.6 ended long ago)"},
+		{"python", "2.6.9", "(python 2.6 ended long ago)"},
+		{"nodejs", "14.21.3", "(nodejs 14 ended long ago)"},
+		{"golang", "1.20.14", "(go 1.20 ended long ago)"},
+		{"nodejs", "19.9.0", ""}, // newer than the oldest cycle: a missing cycle, not an old one
+		{"deno", "2.1.4", ""},    // not tracked
+	}
+	for _, tt := range tests {
+		got := d.Warning(Product(tt.name), tt.version, now)
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("Warning(%s, %s) = %q, want %q", tt.name, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCached(t *testing.T) {
+	store := cache.Store{Dir: t.TempDir()}
+	for _, product := range Products {
+		data := `[{"cycle": "1", "releaseDate": "2020-01-01", "eol": true}]`
+		if err := store.Write(cacheKey(product), cache.Entry{Data: []byte(data), FetchedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := Load(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(d.Source, "cached") {
+		t.Errorf("Source = %q, want cached", d.Source)
+	}
+	if got := d.Warning("python", "1.2.3", time.Now()); !strings.Contains(got, "past its end-of-life") {
+		t.Errorf("Warning(python 1.2.3) = %q, want past end-of-life", got)
+	}
+	if _, ok := d.Lookup("python", "3.12.8"); ok {
+		t.Errorf("Lookup(python 3.12.8) found a cycle in the cached dataset, want the bundled one replaced")
+	}
+}
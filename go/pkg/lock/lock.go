@@ -0,0 +1,229 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/daneroo/dotfiles/go/pkg/asdf"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/actual"
+	"github.com/daneroo/dotfiles/go/pkg/brewdeps/types"
+	"github.com/daneroo/dotfiles/go/pkg/config"
+	"github.com/daneroo/dotfiles/go/pkg/npm"
+)
+
+const header = "# Generated by `checkdeps lock` - do not edit by hand\n# Install exactly these versions with `checkdeps apply --locked`\n\n"
+
+// Lock records the concrete versions that the specs in config.yaml resolved to,
+// so that every machine converges on the same versions.
+type Lock struct {
+	Asdf map[string][]string `yaml:"asdf"`
+	// AsdfSpecs are the specs each plugin's versions were resolved from,
+	// so that a changed spec (python "3.12" -> "3.13") is not served stale versions
+	AsdfSpecs map[string][]string `yaml:"asdf_specs,omitempty"`
+	Homebrew  struct {
+		Formulae map[string]string `yaml:"formulae"`
+		Casks    map[string]string `yaml:"casks"`
+	} `yaml:"homebrew"`
+	Npm map[string]string `yaml:"npm"`
+}
+
+// PathFor returns the lock file path for a config file: config.yaml -> config.lock
+func PathFor(configFile string) string {
+	return strings.TrimSuffix(configFile, filepath.Ext(configFile)) + ".lock"
+}
+
+// Load reads a lock file
+func Load(lockFile string) (*Lock, error) {
+	out, err := os.ReadFile(lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w\nRun `checkdeps lock` first", lockFile, err)
+	}
+	var l Lock
+	if err := yaml.Unmarshal(out, &l); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", lockFile, err)
+	}
+	fmt.Printf("✓ - Lock file loaded (%s)\n", lockFile)
+	return &l, nil
+}
+
+// Write writes the lock file
+func (l *Lock) Write(lockFile string) error {
+	out, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(lockFile, append([]byte(header), out...), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", lockFile, err)
+	}
+	fmt.Printf("✓ - Lock file written (%s)\n", lockFile)
+	return nil
+}
+
+// Generate builds a lock from the configuration:
+//   - asdf: the version specs resolved to concrete versions
+//   - homebrew, npm: the installed versions of the configured packages
+//
+// Brew and npm packages that are not installed cannot be locked; they are
+// reported and left out, so install them before locking.
+func Generate(cfg *config.Config, asdfOpts asdf.Options) (*Lock, error) {
+	var l Lock
+
+	fmt.Printf("\n## Locking asdf versions\n\n")
+	resolved, err := asdf.Resolve(cfg.Asdf, asdfOpts)
+	if err != nil {
+		return nil, err
+	}
+	l.Asdf = resolved
+	l.AsdfSpecs = make(map[string][]string)
+	for plugin := range resolved {
+		l.AsdfSpecs[plugin] = cfg.Asdf[plugin]
+	}
+
+	fmt.Printf("\n## Locking brew versions\n\n")
+	brewVersions, err := actual.GetVersions(cfg.Homebrew)
+	if err != nil {
+		return nil, err
+	}
+	l.Homebrew.Formulae = make(map[string]string)
+	l.Homebrew.Casks = make(map[string]string)
+	for _, pkg := range cfg.Homebrew {
+		v, ok := brewVersions[pkg]
+		if !ok {
+			fmt.Printf("✗ - %s is not installed, not locked\n", pkg.Name)
+			continue
+		}
+		if pkg.IsCask {
+			l.Homebrew.Casks[pkg.Name] = v
+		} else {
+			l.Homebrew.Formulae[pkg.Name] = v
+		}
+	}
+	fmt.Printf("✓ - Locked %d formulae and %d casks\n", len(l.Homebrew.Formulae), len(l.Homebrew.Casks))
+
+	fmt.Printf("\n## Locking npm versions\n\n")
+	npmVersions, err := npm.InstalledVersions()
+	if err != nil {
+		return nil, err
+	}
+	l.Npm = make(map[string]string)
+	for _, pkg := range cfg.Npm {
+		v, ok := npmVersions[pkg]
+		if !ok {
+			fmt.Printf("✗ - npm: %s is not installed, not locked\n", pkg)
+			continue
+		}
+		l.Npm[pkg] = v
+	}
+	fmt.Printf("✓ - Locked %d npm packages\n", len(l.Npm))
+
+	return &l, nil
+}
+
+// AsdfVersions returns the locked versions of the plugins whose specs did not
+// change since locking: the others must be resolved again (see ReportConfigDrift).
+// Lock files written before specs were recorded are trusted as they are.
+func (l *Lock) AsdfVersions(cfg *config.Config) map[string][]string {
+	versions := make(map[string][]string)
+	for plugin, locked := range l.Asdf {
+		if specs, ok := cfg.Asdf[plugin]; !ok || !l.specsChanged(plugin, specs) {
+			versions[plugin] = locked
+		}
+	}
+	return versions
+}
+
+// specsChanged tells whether the configured specs of a plugin differ from those it was locked for
+func (l *Lock) specsChanged(plugin string, specs []string) bool {
+	if l.AsdfSpecs == nil {
+		return false // not recorded
+	}
+	locked, ok := l.AsdfSpecs[plugin]
+	return !ok || !slices.Equal(locked, specs)
+}
+
+func formatSpecs(specs []string) string {
+	return "[" + strings.Join(specs, ", ") + "]"
+}
+
+// brewVersion returns the locked version of a brew package
+func (l *Lock) brewVersion(pkg types.Package) (string, bool) {
+	if pkg.IsCask {
+		v, ok := l.Homebrew.Casks[pkg.Name]
+		return v, ok
+	}
+	v, ok := l.Homebrew.Formulae[pkg.Name]
+	return v, ok
+}
+
+// ReportConfigDrift reports entries of the configuration that are missing
+// from the lock file and vice versa, i.e. config.yaml changed since the last lock.
+// It returns true if any drift was found.
+func (l *Lock) ReportConfigDrift(cfg *config.Config) bool {
+	var drift []string
+	for plugin, specs := range cfg.Asdf {
+		switch {
+		case l.Asdf[plugin] == nil:
+			drift = append(drift, fmt.Sprintf("asdf plugin %s is not locked", plugin))
+		case l.specsChanged(plugin, specs):
+			drift = append(drift, fmt.Sprintf("asdf plugin %s specs changed: locked for %s, configured %s",
+				plugin, formatSpecs(l.AsdfSpecs[plugin]), formatSpecs(specs)))
+		}
+	}
+	if l.AsdfSpecs == nil && len(l.Asdf) > 0 {
+		drift = append(drift, "asdf specs are not recorded: spec changes cannot be detected")
+	}
+	for plugin := range l.Asdf {
+		if _, ok := cfg.Asdf[plugin]; !ok {
+			drift = append(drift, fmt.Sprintf("asdf plugin %s is locked but no longer configured", plugin))
+		}
+	}
+	for _, pkg := range cfg.Homebrew {
+		if _, ok := l.brewVersion(pkg); !ok {
+			drift = append(drift, fmt.Sprintf("brew %s is not locked", pkg.Name))
+		}
+	}
+	for _, pkg := range cfg.Npm {
+		if _, ok := l.Npm[pkg]; !ok {
+			drift = append(drift, fmt.Sprintf("npm %s is not locked", pkg))
+		}
+	}
+
+	if len(drift) == 0 {
+		fmt.Printf("✓ - Lock file matches the configuration\n")
+		return false
+	}
+	sort.Strings(drift)
+	fmt.Printf("△ - Lock file is out of date with the configuration: (%d entries)\n", len(drift))
+	for _, d := range drift {
+		fmt.Printf(" - %s\n", d)
+	}
+	fmt.Printf(" Run: checkdeps lock\n")
+	return true
+}
+
+// ReportBrewDrift compares installed brew versions (see actual.GetVersions) with the lock file.
+// Homebrew can only install the current version of a formula, so drift is
+// reported with a hint rather than fixed.
+func (l *Lock) ReportBrewDrift(desired []types.Package, installed map[types.Package]string) {
+	drifted := false
+	for _, pkg := range desired {
+		want, locked := l.brewVersion(pkg)
+		have, ok := installed[pkg]
+		if !locked || !ok || have == want {
+			continue
+		}
+		drifted = true
+		fmt.Printf("✗ - lock drift: %s is %s, locked at %s\n", pkg.Name, have, want)
+	}
+	if drifted {
+		fmt.Printf(" brew cannot install older versions: upgrade, then re-lock with: checkdeps lock\n")
+	} else {
+		fmt.Printf("✓ - Installed casks/formulae match the lock file\n")
+	}
+}
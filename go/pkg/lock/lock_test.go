@@ -0,0 +1,82 @@
+package lock
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/daneroo/dotfiles/go/pkg/config"
+)
+
+func TestPathFor(t *testing.T) {
+	if got := PathFor("config.yaml"); got != "config.lock" {
+		t.Errorf("PathFor(config.yaml) = %s, want config.lock", got)
+	}
+	if got := PathFor("hosts/galois.yaml"); got != "hosts/galois.lock" {
+		t.Errorf("PathFor(hosts/galois.yaml) = %s, want hosts/galois.lock", got)
+	}
+}
+
+func TestWriteLoad(t *testing.T) {
+	var l Lock
+	l.Asdf = map[string][]string{"python": {"3.11.11", "3.12.8"}}
+	l.AsdfSpecs = map[string][]string{"python": {"3.11", "3.12"}}
+	l.Homebrew.Formulae = map[string]string{"git": "2.47.1"}
+	l.Homebrew.Casks = map[string]string{"vlc": "3.0.21"}
+	l.Npm = map[string]string{"eslint": "9.17.0"}
+
+	path := filepath.Join(t.TempDir(), "config.lock")
+	if err := l.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, l) {
+		t.Errorf("Load() = %+v, want %+v", *got, l)
+	}
+}
+
+func TestReportConfigDrift(t *testing.T) {
+	var l Lock
+	l.Asdf = map[string][]string{"python": {"3.12.8"}}
+	l.AsdfSpecs = map[string][]string{"python": {"3.12"}}
+	l.Homebrew.Formulae = map[string]string{"git": "2.47.1"}
+	l.Npm = map[string]string{"eslint": "9.17.0"}
+
+	cfg := &config.Config{
+		Homebrew: []config.BrewPackage{{Name: "git"}},
+		Asdf:     map[string][]string{"python": {"3.12"}},
+		Npm:      []string{"eslint"},
+	}
+	if l.ReportConfigDrift(cfg) {
+		t.Errorf("expected no drift")
+	}
+
+	cfg.Homebrew = append(cfg.Homebrew, config.BrewPackage{Name: "vlc", IsCask: true})
+	if !l.ReportConfigDrift(cfg) {
+		t.Errorf("expected drift for unlocked cask")
+	}
+}
+
+func TestAsdfSpecsDrift(t *testing.T) {
+	var l Lock
+	l.Asdf = map[string][]string{"python": {"3.12.8"}, "nodejs": {"22.12.0"}}
+	l.AsdfSpecs = map[string][]string{"python": {"3.12"}, "nodejs": {"lts"}}
+	cfg := &config.Config{Asdf: map[string][]string{"python": {"3.13"}, "nodejs": {"lts"}}}
+
+	if !l.ReportConfigDrift(cfg) {
+		t.Errorf("expected drift for changed python specs")
+	}
+	want := map[string][]string{"nodejs": {"22.12.0"}}
+	if got := l.AsdfVersions(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("AsdfVersions() = %v, want %v (python must be resolved again)", got, want)
+	}
+
+	// lock files written before specs were recorded are trusted as they are
+	l.AsdfSpecs = nil
+	if got := l.AsdfVersions(cfg); !reflect.DeepEqual(got, l.Asdf) {
+		t.Errorf("AsdfVersions() = %v, want %v", got, l.Asdf)
+	}
+}
@@ -0,0 +1,235 @@
+package nodejs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+	"github.com/daneroo/dotfiles/go/pkg/version"
+)
+
+const (
+	// DefaultBaseURL serves index.json, the list of all Node.js releases
+	DefaultBaseURL = "https://nodejs.org/dist"
+	// DefaultScheduleURL is the release schedule, which holds the EOL dates
+	DefaultScheduleURL = "https://raw.githubusercontent.com/nodejs/Release/main/schedule.json"
+	// DefaultTTL is how long a cached index is used without revalidation
+	DefaultTTL = 6 * time.Hour
+
+	indexKey    = "nodejs/index.json"
+	scheduleKey = "nodejs/schedule.json"
+)
+
+// Release is one entry of the Node.js release index, enriched with
+// the end-of-life date of its major line when the schedule is available.
+type Release struct {
+	Version string    // without the leading "v", e.g. "22.12.0"
+	Date    string    // release date, e.g. "2024-12-03"
+	LTS     string    // lowercased LTS codename, e.g. "jod", or "" if not an LTS release
+	EOL     time.Time // zero if unknown
+}
+
+// Client fetches the Node.js release index and schedule,
+// caching both on disk and falling back to the cache when offline.
+type Client struct {
+	BaseURL     string
+	ScheduleURL string
+	TTL         time.Duration
+	Cache       cache.Store
+	HTTP        *http.Client
+	// Offline never touches the network, only the cache is used
+	Offline bool
+	// releases memoizes Releases: the index is read at most once per Client
+	releases []Release
+	// warnings are the problems worked around (a stale cache, an unparseable schedule),
+	// kept for the caller to report (see Warnings): Releases may run in a collect step
+	warnings []string
+}
+
+// Warnings returns the warnings since the last call, and clears them
+func (c *Client) Warnings() []string {
+	warnings := c.warnings
+	c.warnings = nil
+	return warnings
+}
+
+func (c *Client) warnf(format string, args ...any) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// NewClient returns a client for nodejs.org, caching in the given store.
+func NewClient(store cache.Store) *Client {
+	return &Client{
+		BaseURL:     DefaultBaseURL,
+		ScheduleURL: DefaultScheduleURL,
+		TTL:         DefaultTTL,
+		Cache:       store,
+		HTTP:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type indexEntry struct {
+	Version string `json:"version"`
+	Date    string `json:"date"`
+	// LTS is a release codename like "Jod" for LTS releases, otherwise false
+	LTS interface{} `json:"lts"`
+}
+
+type scheduleEntry struct {
+	End string `json:"end"`
+}
+
+// Releases returns all releases, newest first (the order of index.json).
+// EOL dates are filled in on a best-effort basis: a schedule that cannot
+// be fetched (nor found in the cache) is not an error.
+func (c *Client) Releases() ([]Release, error) {
+	if c.releases != nil {
+		return c.releases, nil
+	}
+	data, err := c.fetch(indexKey, strings.TrimSuffix(c.BaseURL, "/")+"/index.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Node.js versions: %w", err)
+	}
+	var entries []indexEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse Node.js versions: %w", err)
+	}
+
+	schedule := map[string]scheduleEntry{}
+	if data, err := c.fetch(scheduleKey, c.ScheduleURL); err == nil {
+		if err := json.Unmarshal(data, &schedule); err != nil {
+			c.warnf("ignoring unparseable Node.js release schedule: %v", err)
+		}
+	}
+
+	releases := make([]Release, 0, len(entries))
+	for _, e := range entries {
+		r := Release{Version: strings.TrimPrefix(e.Version, "v"), Date: e.Date}
+		if codename, ok := e.LTS.(string); ok {
+			r.LTS = strings.ToLower(codename)
+		}
+		if v, err := version.Parse(r.Version); err == nil && len(v.Release) > 0 {
+			if s, ok := schedule[fmt.Sprintf("v%d", v.Release[0])]; ok {
+				r.EOL, _ = time.Parse("2006-01-02", s.End)
+			}
+		}
+		releases = append(releases, r)
+	}
+	c.releases = releases
+	return releases, nil
+}
+
+// Resolve returns the newest release matching the spec:
+//   - "latest": the latest release
+//   - "lts": the latest LTS release
+//   - "lts/<codename>": the latest release of that LTS line, e.g. "lts/jod"
+//   - "X[.Y[.Z]]": the latest release matching the prefix
+func (c *Client) Resolve(spec string) (Release, error) {
+	releases, err := c.Releases()
+	if err != nil {
+		return Release{}, err
+	}
+	return resolve(releases, spec)
+}
+
+func resolve(releases []Release, spec string) (Release, error) {
+	var match func(Release) bool
+	switch {
+	case spec == "latest":
+		match = func(Release) bool { return true }
+	case spec == "lts":
+		match = func(r Release) bool { return r.LTS != "" }
+	case strings.HasPrefix(spec, "lts/"):
+		codename := strings.ToLower(strings.TrimPrefix(spec, "lts/"))
+		match = func(r Release) bool { return r.LTS == codename }
+	default:
+		if _, err := version.Parse(spec); err != nil {
+			return Release{}, fmt.Errorf("unsupported Node.js version spec: %s", spec)
+		}
+		match = func(r Release) bool {
+			v, err := version.Parse(r.Version)
+			return err == nil && v.HasPrefix(spec)
+		}
+	}
+
+	// Do not assume index.json order: pick the highest matching version
+	var best *Release
+	for i, r := range releases {
+		if match(r) && (best == nil || version.Compare(r.Version, best.Version) > 0) {
+			best = &releases[i]
+		}
+	}
+	if best == nil {
+		return Release{}, fmt.Errorf("no Node.js version found matching %s", spec)
+	}
+	return *best, nil
+}
+
+// fetch returns the body at url, using the cache:
+//   - a cached entry younger than TTL is returned as is
+//   - otherwise the url is revalidated with If-None-Match (ETag)
+//   - if the request fails, a stale cached entry is returned with a warning
+//   - offline, the cached entry is returned, with a warning when stale
+func (c *Client) fetch(key, url string) ([]byte, error) {
+	cached, cacheErr := c.Cache.Read(key)
+	hasCache := cacheErr == nil
+	if hasCache && c.Offline && cached.Age() >= c.TTL {
+		c.warnf("offline: using %s cached %s ago, new releases may be missing", key, cache.FormatAge(cached.Age()))
+	}
+	if hasCache && (c.Offline || cached.Age() < c.TTL) {
+		return cached.Data, nil
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("offline and %s is not cached in %s", key, c.Cache.Dir)
+	}
+
+	data, err := c.download(key, url, cached, hasCache)
+	if err != nil {
+		if hasCache {
+			c.warnf("%v; using cached %s (age %s)", err, key, cached.Age().Round(time.Minute))
+			return cached.Data, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *Client) download(key, url string, cached cache.Entry, hasCache bool) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if hasCache && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && hasCache:
+		if err := c.Cache.Touch(key, cached); err != nil {
+			return nil, err
+		}
+		return cached.Data, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+	entry := cache.Entry{Data: data, ETag: resp.Header.Get("ETag"), FetchedAt: time.Now()}
+	if err := c.Cache.Write(key, entry); err != nil {
+		// a broken cache should not prevent resolution
+		c.warnf("%v", err)
+	}
+	return data, nil
+}
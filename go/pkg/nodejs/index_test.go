@@ -0,0 +1,158 @@
+package nodejs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daneroo/dotfiles/go/pkg/cache"
+)
+
+const testIndex = `[
+  {"version": "v23.5.0", "date": "2024-12-19", "lts": false},
+  {"version": "v22.12.0", "date": "2024-12-03", "lts": "Jod"},
+  {"version": "v22.2.0", "date": "2024-05-15", "lts": false},
+  {"version": "v20.18.1", "date": "2024-11-20", "lts": "Iron"},
+  {"version": "v18.20.5", "date": "2024-11-12", "lts": "Hydrogen"}
+]`
+
+const testSchedule = `{
+  "v18": {"start": "2022-04-19", "end": "2025-04-30", "codename": "Hydrogen"},
+  "v22": {"start": "2024-04-24", "end": "2027-04-30", "codename": "Jod"}
+}`
+
+// newTestServer serves index.json and schedule.json with an ETag,
+// counting the requests that returned a body.
+func newTestServer(t *testing.T, served *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	handle := func(path, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			served.Add(1)
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(body))
+		})
+	}
+	handle("/dist/index.json", testIndex)
+	handle("/schedule.json", testSchedule)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestClient(srv *httptest.Server, dir string) *Client {
+	c := NewClient(cache.Store{Dir: dir})
+	c.BaseURL = srv.URL + "/dist"
+	c.ScheduleURL = srv.URL + "/schedule.json"
+	return c
+}
+
+func TestResolve(t *testing.T) {
+	var served atomic.Int32
+	c := newTestClient(newTestServer(t, &served), t.TempDir())
+
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "latest", want: "23.5.0"},
+		{spec: "lts", want: "22.12.0"},
+		{spec: "lts/iron", want: "20.18.1"},
+		{spec: "lts/Hydrogen", want: "18.20.5"},
+		{spec: "22", want: "22.12.0"},
+		{spec: "22.2", want: "22.2.0"},
+		{spec: "2", wantErr: true}, // not a string prefix of "22.x"
+		{spec: "lts/argon", wantErr: true},
+		{spec: "stable", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := c.Resolve(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err == nil && got.Version != tt.want {
+				t.Errorf("Resolve(%q) = %s, want %s", tt.spec, got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestEOL(t *testing.T) {
+	var served atomic.Int32
+	c := newTestClient(newTestServer(t, &served), t.TempDir())
+
+	r, err := c.Resolve("lts/hydrogen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC); !r.EOL.Equal(want) {
+		t.Errorf("EOL = %v, want %v", r.EOL, want)
+	}
+	r, _ = c.Resolve("20")
+	if !r.EOL.IsZero() {
+		t.Errorf("expected unknown EOL for %s, got %v", r.Version, r.EOL)
+	}
+}
+
+func TestCache(t *testing.T) {
+	var served atomic.Int32
+	srv := newTestServer(t, &served)
+	dir := t.TempDir()
+
+	c := newTestClient(srv, dir)
+	if _, err := c.Resolve("lts"); err != nil {
+		t.Fatal(err)
+	}
+	if got := served.Load(); got != 2 {
+		t.Fatalf("first run served %d bodies, want 2 (index and schedule)", got)
+	}
+
+	// within TTL: no requests at all
+	if _, err := c.Resolve("lts"); err != nil {
+		t.Fatal(err)
+	}
+	if got := served.Load(); got != 2 {
+		t.Errorf("fresh cache served %d bodies, want 2", got)
+	}
+
+	// expired TTL: revalidated with ETag, 304 keeps the cached body
+	c.TTL = 0
+	if _, err := c.Resolve("lts"); err != nil {
+		t.Fatal(err)
+	}
+	if got := served.Load(); got != 2 {
+		t.Errorf("revalidation served %d bodies, want 2", got)
+	}
+
+	// server gone: fall back to the stale cache
+	srv.Close()
+	if r, err := c.Resolve("latest"); err != nil || r.Version != "23.5.0" {
+		t.Errorf("offline fallback = %v, %v; want 23.5.0", r.Version, err)
+	}
+
+	// explicit offline mode uses the cache of any age, with a warning when stale
+	stale := newTestClient(srv, dir)
+	stale.Offline, stale.TTL = true, 0
+	if _, err := stale.Resolve("lts"); err != nil {
+		t.Fatal(err)
+	}
+	if warnings := stale.Warnings(); len(warnings) == 0 || !strings.Contains(warnings[0], "nodejs/index.json cached") {
+		t.Errorf("Warnings() = %q, want the age of the cached index", warnings)
+	}
+
+	// explicit offline mode without a cache fails
+	empty := newTestClient(srv, t.TempDir())
+	empty.Offline = true
+	if _, err := empty.Resolve("lts"); err == nil {
+		t.Errorf("expected an error resolving offline with an empty cache")
+	}
+}
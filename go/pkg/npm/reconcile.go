@@ -7,25 +7,49 @@ import (
 	"slices"
 	"sort"
 	"strings"
+
+	"github.com/daneroo/dotfiles/go/pkg/collect"
 )
 
+// Options controls which versions Reconcile installs
+type Options struct {
+	// Locked maps packages to the exact versions recorded in the lock file.
+	// When set, these versions are installed instead of the latest ones,
+	// and outdated packages are not updated past their locked version
+	Locked map[string]string
+	// DryRun (plan mode) shows the npm install commands without running them
+	DryRun bool
+}
+
 // Reconcile performs a complete reconciliation cycle for npm global packages:
 // 1. Check if npm is installed
 // 2. Get actual state (installed packages)
 // 3. Compare with desired state
 // 4. Take actions to reconcile differences
-func Reconcile(desiredPackages []string) error {
+func Reconcile(desiredPackages []string, opts Options) error {
 	// Check if npm is installed
 	if err := exec.Command("command", "-v", "npm").Run(); err != nil {
 		return fmt.Errorf("npm is not installed")
 	}
 	fmt.Printf("✓ - npm is installed\n")
 
-	// Get actual installed packages
-	actual, err := getInstalledPackages()
-	if err != nil {
+	// Get actual installed packages, and outdated ones, concurrently
+	var installedVersions map[string]string
+	var outdated map[string]outdatedPackage
+	steps := []collect.Step{{Name: "npm ls -g", Run: func() (err error) {
+		installedVersions, err = InstalledVersions()
+		return err
+	}}}
+	if opts.Locked == nil {
+		steps = append(steps, collect.Step{Name: "npm outdated -g", Run: func() (err error) {
+			outdated, err = outdatedPackages()
+			return err
+		}})
+	}
+	if err := collect.Run(collect.Workers, steps...); err != nil {
 		return err
 	}
+	actual := packageNames(installedVersions)
 
 	// Reconcile differences
 	missing, extra := reconcilePackages(desiredPackages, actual)
@@ -40,13 +64,17 @@ func Reconcile(desiredPackages []string) error {
 
 	// Install missing packages
 	fmt.Printf("\n") // separator
-	if err := performPackageActions(missing, extra); err != nil {
+	if err := performPackageActions(missing, extra, opts); err != nil {
 		return err
 	}
 
-	// Check for updates
+	// Check for updates, or for drift from the lock file
 	fmt.Printf("\n") // separator
-	if err := checkOutdated(); err != nil {
+	if opts.Locked != nil {
+		if err := applyLocked(desiredPackages, installedVersions, opts); err != nil {
+			return err
+		}
+	} else if err := checkOutdated(outdated, opts); err != nil {
 		return err
 	}
 	//  deprecateCorepackPnpm
@@ -58,9 +86,9 @@ func Reconcile(desiredPackages []string) error {
 	return nil
 }
 
-// getInstalledPackages returns a list of globally installed npm packages
+// InstalledVersions returns the globally installed npm packages and their versions
 // by running npm ls -g --json and parsing the output
-func getInstalledPackages() ([]string, error) {
+func InstalledVersions() (map[string]string, error) {
 	out, err := exec.Command("npm", "ls", "-g", "--json", "--depth=0").Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list global packages: %w", err)
@@ -76,12 +104,21 @@ func getInstalledPackages() ([]string, error) {
 		return nil, fmt.Errorf("failed to parse npm output: %w", err)
 	}
 
+	versions := make(map[string]string)
+	for pkg, info := range npmOutput.Dependencies {
+		versions[pkg] = info.Version
+	}
+	return versions, nil
+}
+
+// packageNames returns the sorted package names of a package -> version map
+func packageNames(versions map[string]string) []string {
 	var packages []string
-	for pkg := range npmOutput.Dependencies {
+	for pkg := range versions {
 		packages = append(packages, pkg)
 	}
 	sort.Strings(packages) // Sort for consistent output
-	return packages, nil
+	return packages
 }
 
 // reconcilePackages determines which packages need to be installed/removed
@@ -113,12 +150,17 @@ func reconcilePackages(desired, actual []string) (missing, extra []string) {
 	return missing, extra
 }
 
-// performPackageActions installs missing packages
-func performPackageActions(missing, extra []string) error {
+// performPackageActions installs missing packages, at their locked version if any
+func performPackageActions(missing, extra []string, opts Options) error {
 	// Install missing packages
 	for _, pkg := range missing {
+		if opts.DryRun {
+			fmt.Printf("✗ - npm: %s is missing\n", pkg)
+			fmt.Printf("  npm install -g %s\n", lockedSpec(pkg, opts.Locked))
+			continue
+		}
 		fmt.Printf("✗ - npm: %s is missing. Installing...\n", pkg)
-		if err := exec.Command("npm", "install", "-g", pkg).Run(); err != nil {
+		if err := exec.Command("npm", "install", "-g", lockedSpec(pkg, opts.Locked)).Run(); err != nil {
 			return fmt.Errorf("failed to install %s: %w", pkg, err)
 		}
 		fmt.Printf("  ✓ - npm: %s was successfully installed\n", pkg)
@@ -136,35 +178,99 @@ func performPackageActions(missing, extra []string) error {
 	return nil
 }
 
-// checkOutdated checks for available updates in global packages
-func checkOutdated() error {
+// lockedSpec returns pkg@version when pkg is locked, pkg otherwise
+func lockedSpec(pkg string, locked map[string]string) string {
+	if v, ok := locked[pkg]; ok {
+		return pkg + "@" + v
+	}
+	return pkg
+}
+
+// applyLocked reinstalls packages whose installed version drifted from the lock file,
+// and reports desired packages missing from the lock file
+func applyLocked(desired []string, installed map[string]string, opts Options) error {
+	drifted := false
+	for _, pkg := range desired {
+		want, ok := opts.Locked[pkg]
+		if !ok {
+			fmt.Printf("△ - lock drift: npm: %s is not in the lock file\n", pkg)
+			drifted = true
+			continue
+		}
+		have, ok := installed[pkg]
+		if !ok || have == want {
+			continue // missing packages were installed at their locked version
+		}
+		drifted = true
+		if opts.DryRun {
+			fmt.Printf("✗ - lock drift: npm: %s is %s, locked at %s\n", pkg, have, want)
+			fmt.Printf("  npm install -g %s@%s\n", pkg, want)
+			continue
+		}
+		fmt.Printf("✗ - lock drift: npm: %s is %s, locked at %s. Installing...\n", pkg, have, want)
+		if err := exec.Command("npm", "install", "-g", pkg+"@"+want).Run(); err != nil {
+			return fmt.Errorf("failed to install %s@%s: %w", pkg, want, err)
+		}
+		fmt.Printf("  ✓ - npm: %s@%s was successfully installed\n", pkg, want)
+	}
+	if !drifted {
+		fmt.Printf("✓ - All global packages match the lock file\n")
+	}
+	return nil
+}
+
+// outdatedPackage is an entry of npm outdated -g --json
+type outdatedPackage struct {
+	Current string `json:"current"`
+	Wanted  string `json:"wanted"`
+	Latest  string `json:"latest"`
+}
+
+// outdatedPackages returns the outdated global packages
+func outdatedPackages() (map[string]outdatedPackage, error) {
 	// Get outdated packages in JSON format
 	out, err := exec.Command("npm", "outdated", "-g", "--json").Output()
 	if err != nil {
 		// npm outdated returns exit code 1 if updates are available
-		if len(out) > 0 {
-			var outdated map[string]struct {
-				Current string `json:"current"`
-				Wanted  string `json:"wanted"`
-				Latest  string `json:"latest"`
-			}
-			if err := json.Unmarshal(out, &outdated); err != nil {
-				return fmt.Errorf("failed to parse npm outdated output: %w", err)
-			}
-
-			// Update each outdated package
-			for pkg, info := range outdated {
-				fmt.Printf("✗ - npm: %s needs update (%s -> %s). Installing...\n", pkg, info.Current, info.Latest)
-				if err := exec.Command("npm", "install", "-g", pkg).Run(); err != nil {
-					return fmt.Errorf("failed to update %s: %w", pkg, err)
-				}
-				fmt.Printf("  ✓ - npm: %s was successfully updated\n", pkg)
-			}
-			return nil
+		if len(out) == 0 {
+			return nil, fmt.Errorf("failed to check for updates: %w", err)
+		}
+		var outdated map[string]outdatedPackage
+		if err := json.Unmarshal(out, &outdated); err != nil {
+			return nil, fmt.Errorf("failed to parse npm outdated output: %w", err)
+		}
+		return outdated, nil
+	}
+	return nil, nil
+}
+
+// checkOutdated installs the updates of outdated global packages
+// (plan mode: only shows the commands)
+func checkOutdated(outdated map[string]outdatedPackage, opts Options) error {
+	if len(outdated) == 0 {
+		fmt.Printf("✓ - All global packages are up to date\n")
+		return nil
+	}
+	var packages []string
+	for pkg := range outdated {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages) // Sort for consistent output
+
+	// Update each outdated package
+	for _, pkg := range packages {
+		info := outdated[pkg]
+		if opts.DryRun {
+			fmt.Printf("✗ - npm: %s needs update (%s -> %s)\n", pkg, info.Current, info.Latest)
+			fmt.Printf("  npm install -g %s\n", pkg)
+			continue
+		}
+		fmt.Printf("✗ - npm: %s needs update (%s -> %s). Installing...\n", pkg, info.Current, info.Latest)
+		if err := exec.Command("npm", "install", "-g", pkg).Run(); err != nil {
+			return fmt.Errorf("failed to update %s: %w", pkg, err)
 		}
-		return fmt.Errorf("failed to check for updates: %w", err)
+		fmt.Printf("  ✓ - npm: %s was successfully updated\n", pkg)
 	}
-	fmt.Printf("✓ - All global packages are up to date\n")
 	return nil
 }
 
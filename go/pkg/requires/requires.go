@@ -0,0 +1,180 @@
+// Package requires orders the configuration sections by their cross-manager
+// prerequisites, declared in the config's requires section:
+//
+//	requires:
+//	  asdf:python: ["brew:openssl@3", "brew:readline"]
+//	  npm: ["brew:node"]
+//
+// Sections form a DAG: a section runs after every section it (or one of its
+// packages) requires packages from.
+package requires
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/daneroo/dotfiles/go/pkg/config"
+)
+
+// Ref is a package of a manager, written "<manager>:<name>", e.g. "brew:openssl@3"
+type Ref struct {
+	Manager string
+	Name    string
+}
+
+func (r Ref) String() string {
+	return r.Manager + ":" + r.Name
+}
+
+// ParseRef parses "<manager>:<name>"
+func ParseRef(s string) (Ref, error) {
+	manager, name, ok := strings.Cut(s, ":")
+	if !ok || manager == "" || name == "" {
+		return Ref{}, fmt.Errorf("invalid package %q: must be <manager>:<name>", s)
+	}
+	return Ref{Manager: manager, Name: name}, nil
+}
+
+// Installed reports whether a package is installed
+type Installed func(Ref) (bool, error)
+
+// Graph holds the prerequisites of sections and packages
+type Graph struct {
+	// requires maps a section or package ("asdf", "asdf:python") to its prerequisites
+	requires map[string][]Ref
+}
+
+// New builds the graph from the config's requires section
+func New(requires map[string][]string) (*Graph, error) {
+	g := &Graph{requires: make(map[string][]Ref)}
+	for key, prerequisites := range requires {
+		for _, p := range prerequisites {
+			ref, err := ParseRef(p)
+			if err != nil {
+				return nil, fmt.Errorf("requires %s: %w", key, err)
+			}
+			g.requires[key] = append(g.requires[key], ref)
+		}
+	}
+	return g, nil
+}
+
+// section returns the section of a requires key: "asdf:python" -> "asdf"
+func section(key string) string {
+	s, _, _ := strings.Cut(key, ":")
+	return s
+}
+
+// Order returns the sections in dependency order. Independent sections keep
+// their default order (config.Sections), so that without requires the order is
+// brew, asdf, npm, completions. A cycle is an error naming the sections involved.
+func (g *Graph) Order() ([]string, error) {
+	// after[s] are the sections that must run before s
+	after := make(map[string]map[string]bool)
+	for key, refs := range g.requires {
+		for _, ref := range refs {
+			s := section(key)
+			if after[s] == nil {
+				after[s] = make(map[string]bool)
+			}
+			after[s][ref.Manager] = true
+		}
+	}
+
+	var order []string
+	done := make(map[string]bool)
+	for len(order) < len(config.Sections) {
+		progress := false
+		for _, s := range config.Sections {
+			if done[s] || !allDone(after[s], done) {
+				continue
+			}
+			order = append(order, s)
+			done[s] = true
+			progress = true
+			break // restart from the first section, to keep the default order
+		}
+		if !progress {
+			// sections left over are on a cycle, or run after one: only name the former
+			var cycle []string
+			for _, s := range config.Sections {
+				if !done[s] && reaches(after, s, s, make(map[string]bool)) {
+					cycle = append(cycle, s)
+				}
+			}
+			return nil, fmt.Errorf("requires: dependency cycle between sections %s", strings.Join(cycle, ", "))
+		}
+	}
+	return order, nil
+}
+
+// reaches tells whether target must run before section, directly or through other sections
+func reaches(after map[string]map[string]bool, section, target string, seen map[string]bool) bool {
+	for s := range after[section] {
+		if s == target {
+			return true
+		}
+		if !seen[s] {
+			seen[s] = true
+			if reaches(after, s, target, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func allDone(sections map[string]bool, done map[string]bool) bool {
+	for s := range sections {
+		if !done[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// Prerequisites returns the prerequisites of a section and of its packages,
+// keyed by what requires them ("asdf" or "asdf:python"), sorted by key
+func (g *Graph) Prerequisites(sectionName string) (keys []string, refs map[string][]Ref) {
+	refs = make(map[string][]Ref)
+	for key, r := range g.requires {
+		if section(key) == sectionName {
+			keys = append(keys, key)
+			refs[key] = r
+		}
+	}
+	sort.Strings(keys)
+	return keys, refs
+}
+
+// Check verifies that the prerequisites of a section are installed, before it runs.
+// In plan mode (dryRun), the prerequisites sections did not install anything,
+// so missing prerequisites are only reported; otherwise they are an error.
+func (g *Graph) Check(sectionName string, installed Installed, dryRun bool) error {
+	keys, refs := g.Prerequisites(sectionName)
+	var missing []string
+	for _, key := range keys {
+		for _, ref := range refs[key] {
+			ok, err := installed(ref)
+			if err != nil {
+				return fmt.Errorf("checking %s (required by %s): %w", ref, key, err)
+			}
+			switch {
+			case ok:
+				fmt.Printf("✓ - %s requires %s: installed\n", key, ref)
+			case dryRun:
+				fmt.Printf("△ - %s requires %s: not installed yet, the %s section installs it first\n", key, ref, ref.Manager)
+			default:
+				fmt.Printf("✗ - %s requires %s: not installed\n", key, ref)
+				missing = append(missing, fmt.Sprintf("%s (required by %s)", ref, key))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("%s section: missing prerequisites %s", sectionName, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package requires
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		requires map[string][]string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name: "default order",
+			want: []string{"brew", "asdf", "npm", "completions"},
+		},
+		{
+			name:     "package prerequisites keep the default order",
+			requires: map[string][]string{"asdf:python": {"brew:openssl@3"}, "npm": {"asdf:nodejs"}},
+			want:     []string{"brew", "asdf", "npm", "completions"},
+		},
+		{
+			name:     "brew after npm",
+			requires: map[string][]string{"brew:foo": {"npm:bar"}},
+			want:     []string{"asdf", "npm", "brew", "completions"},
+		},
+		{
+			name:     "cycle",
+			requires: map[string][]string{"asdf:python": {"brew:openssl@3"}, "brew": {"asdf:nodejs"}},
+			wantErr:  "dependency cycle between sections brew, asdf",
+		},
+		{
+			name:     "sections after a cycle are not on it",
+			requires: map[string][]string{"asdf:python": {"brew:openssl@3"}, "brew": {"asdf:nodejs"}, "npm": {"asdf:nodejs"}},
+			wantErr:  "dependency cycle between sections brew, asdf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(tt.requires)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := g.Order()
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.wantErr) {
+					t.Fatalf("Order() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	g, err := New(map[string][]string{"asdf:python": {"brew:openssl@3", "brew:readline"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	installed := func(ref Ref) (bool, error) { return ref.Name == "openssl@3", nil }
+
+	if err := g.Check("asdf", installed, true); err != nil {
+		t.Errorf("Check(dryRun) = %v, want nil", err)
+	}
+	err = g.Check("asdf", installed, false)
+	if err == nil || !strings.Contains(err.Error(), "brew:readline (required by asdf:python)") {
+		t.Errorf("Check() = %v, want missing brew:readline", err)
+	}
+	if err := g.Check("npm", installed, false); err != nil {
+		t.Errorf("Check(npm) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,265 @@
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Phase orders the qualifiers that can follow the numeric release segments.
+// The numeric values matter: they define the sort order of qualifiers
+// relative to each other and to a final release.
+type Phase int
+
+const (
+	PhaseDev   Phase = iota // "dev", "snapshot", "nightly"
+	PhaseOther              // unrecognized semver prerelease identifiers, ordered lexically among themselves
+	PhaseAlpha              // "a", "alpha"
+	PhaseBeta               // "b", "beta"
+	PhaseRC                 // "rc", "c", "pre", "preview"
+	PhaseFinal              // no qualifier: a final release
+	PhasePost               // "post", "r", "rev" (PEP 440 post releases)
+)
+
+// Qualifier is one element of the suffix that follows the release segments,
+// e.g. "rc1" in "3.13.0rc1" or "beta.2" in "1.2.0-beta.2".
+type Qualifier struct {
+	Phase Phase
+	Label string // the canonical label of the phase ("a" -> "alpha"), or as written (lowercased) for PhaseOther
+	Num   int
+}
+
+// Version is a parsed version string.
+// Examples of what Parse understands:
+//   - semver: "1.2.3", "1.2.0-beta.2", "1.2.3+build.5"
+//   - PEP 440 (python): "3.13.0rc1", "3.12.0a2", "1.0.post1", "3.12-dev"
+//   - calendar versions: "2024.1", "24.04"
+//   - plugin quirks: a leading "v" (nodejs, deno), python's free-threaded
+//     builds like "3.13.0t" (see Variant)
+type Version struct {
+	Raw        string
+	Release    []int       // numeric release segments: "3.12.1" -> [3 12 1]
+	Qualifiers []Qualifier // empty for a final release
+	Variant    string      // build flavor that is not an ordering qualifier, e.g. "t"
+	Build      string      // semver build metadata after "+", ignored for ordering
+}
+
+var (
+	releasePattern   = regexp.MustCompile(`^(\d+(?:\.\d+)*)`)
+	qualifierPattern = regexp.MustCompile(`^[-._]?([a-z]+)[-._]?(\d*)`)
+	// python free-threaded builds: "3.13.0t", "3.13t-dev"
+	variantPattern = regexp.MustCompile(`^t(?:$|[-._])`)
+)
+
+// phases maps known qualifier labels to their Phase.
+var phases = map[string]Phase{
+	"dev":      PhaseDev,
+	"snapshot": PhaseDev,
+	"nightly":  PhaseDev,
+	"a":        PhaseAlpha,
+	"alpha":    PhaseAlpha,
+	"b":        PhaseBeta,
+	"beta":     PhaseBeta,
+	"c":        PhaseRC,
+	"rc":       PhaseRC,
+	"pre":      PhaseRC,
+	"preview":  PhaseRC,
+	"post":     PhasePost,
+	"r":        PhasePost,
+	"rev":      PhasePost,
+}
+
+// labels maps each known Phase to its canonical label, so that synonyms
+// ("c", "rc", "pre", "preview") are the same qualifier.
+var labels = map[Phase]string{
+	PhaseDev:   "dev",
+	PhaseAlpha: "alpha",
+	PhaseBeta:  "beta",
+	PhaseRC:    "rc",
+	PhasePost:  "post",
+}
+
+// Parse parses a version string. It returns an error when the string does
+// not start with a numeric release, e.g. "system", "miniconda3-4.7.12"
+// or "pypy3.10-7.3.17".
+func Parse(s string) (Version, error) {
+	v := Version{Raw: s}
+	rest := strings.ToLower(strings.TrimSpace(s))
+	rest = strings.TrimPrefix(rest, "v")
+
+	if i := strings.Index(rest, "+"); i >= 0 {
+		v.Build = rest[i+1:]
+		rest = rest[:i]
+	}
+
+	m := releasePattern.FindString(rest)
+	if m == "" {
+		return Version{}, fmt.Errorf("invalid version %q: must start with a numeric release", s)
+	}
+	for _, seg := range strings.Split(m, ".") {
+		n, err := strconv.Atoi(seg)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		v.Release = append(v.Release, n)
+	}
+	rest = rest[len(m):]
+
+	if variantPattern.MatchString(rest) {
+		v.Variant = "t"
+		rest = rest[1:]
+	}
+
+	for rest != "" {
+		qm := qualifierPattern.FindStringSubmatch(rest)
+		if qm == nil {
+			// trailing numeric identifiers, e.g. the "3" in "1.0.0-beta.2.3"
+			if num := strings.TrimLeft(rest, "-._"); num != "" && num != rest {
+				if n, err := strconv.Atoi(num); err == nil {
+					v.Qualifiers = append(v.Qualifiers, Qualifier{Phase: PhaseOther, Num: n})
+					break
+				}
+			}
+			return Version{}, fmt.Errorf("invalid version %q: unexpected %q", s, rest)
+		}
+		q := Qualifier{Label: qm[1], Phase: PhaseOther}
+		if p, ok := phases[qm[1]]; ok {
+			q.Phase, q.Label = p, labels[p]
+		}
+		if qm[2] != "" {
+			q.Num, _ = strconv.Atoi(qm[2])
+		}
+		v.Qualifiers = append(v.Qualifiers, q)
+		rest = rest[len(qm[0]):]
+	}
+	return v, nil
+}
+
+// IsStable reports whether v is a final (or post) release of the default build:
+// no dev/alpha/beta/rc qualifiers and no variant such as python's free-threaded "t".
+func (v Version) IsStable() bool {
+	if v.Variant != "" {
+		return false
+	}
+	for _, q := range v.Qualifiers {
+		if q.Phase < PhaseFinal {
+			return false
+		}
+	}
+	return true
+}
+
+// HasPrefix reports whether the release segments of v start with the
+// segments of prefix, e.g. "3.12.1" has prefix "3.12" but "3.120.0" does not.
+func (v Version) HasPrefix(prefix string) bool {
+	p, err := Parse(prefix)
+	if err != nil || len(p.Qualifiers) > 0 || len(p.Release) > len(v.Release) {
+		return false
+	}
+	for i, n := range p.Release {
+		if v.Release[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare returns -1, 0 or +1 depending on whether a sorts before, equal to, or after b.
+//
+// Ordering rules:
+//   - release segments are compared numerically, missing segments count as 0;
+//     when equal, the shorter release sorts first ("1.2" < "1.2.0")
+//   - qualifiers then follow PEP 440: dev < pre-releases < final < post, with
+//     synonyms being equal ("c1" = "rc1"); pre-release labels that are not
+//     PEP 440's come before alpha, and compare lexically among themselves, as in
+//     SemVer ("1.0.0-gamma" < "1.0.0-x.7" < "1.0.0-alpha")
+//   - the default build sorts before a variant ("3.13.0" < "3.13.0t")
+//   - build metadata is ignored, except as a final tie-breaker
+//   - unparseable strings sort before all versions, lexically among themselves
+func Compare(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	if c := va.Compare(vb); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// Compare compares two parsed versions, see the package level Compare for the rules.
+func (v Version) Compare(o Version) int {
+	if c := compareRelease(v.Release, o.Release); c != 0 {
+		return c
+	}
+	if c := compareQualifiers(v.Qualifiers, o.Qualifiers); c != 0 {
+		return c
+	}
+	if c := strings.Compare(v.Variant, o.Variant); c != 0 {
+		return c
+	}
+	return strings.Compare(v.Build, o.Build)
+}
+
+func compareRelease(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ai, bi int
+		if i < len(a) {
+			ai = a[i]
+		}
+		if i < len(b) {
+			bi = b[i]
+		}
+		if ai != bi {
+			return cmpInt(ai, bi)
+		}
+	}
+	return cmpInt(len(a), len(b))
+}
+
+// compareQualifiers compares qualifiers element-wise; a missing qualifier
+// counts as a final release, so "1.0rc1" < "1.0" < "1.0.post1"
+// and "1.0rc1.dev2" < "1.0rc1". Qualifiers are ordered by phase; only
+// unrecognized labels (PhaseOther) are then compared lexically.
+func compareQualifiers(a, b []Qualifier) int {
+	final := Qualifier{Phase: PhaseFinal}
+	for i := 0; i < len(a) || i < len(b); i++ {
+		qa, qb := final, final
+		if i < len(a) {
+			qa = a[i]
+		}
+		if i < len(b) {
+			qb = b[i]
+		}
+		if qa.Phase != qb.Phase {
+			return cmpInt(int(qa.Phase), int(qb.Phase))
+		}
+		if qa.Phase == PhaseOther {
+			// SemVer: alphanumeric identifiers compare lexically ("gamma" < "x")
+			if c := strings.Compare(qa.Label, qb.Label); c != 0 {
+				return c
+			}
+		}
+		if qa.Num != qb.Num {
+			return cmpInt(qa.Num, qb.Num)
+		}
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.0", "1.10.0", -1},
+		{"1.2", "1.2.0", -1},
+		{"1.2.0", "1.2.0", 0},
+		{"3.13.0rc1", "3.13.0", -1},
+		{"3.13.0a1", "3.13.0b1", -1},
+		{"3.13.0rc1.dev2", "3.13.0rc1", -1},
+		{"1.0.post1", "1.0", 1},
+		{"3.12-dev", "3.12.0", -1},
+		{"1.2.0-alpha.10", "1.2.0-alpha.2", 1},
+		{"1.0.0-x.7", "1.0.0-alpha", -1}, // unrecognized labels come before alpha
+		{"1.0.0-gamma", "1.0.0-x", -1},   // and compare lexically among themselves
+		{"1.0.0-beta", "1.0.0-gamma", 1},
+		{"1.0.0c2", "1.0.0rc1", 1},          // synonyms
+		{"1.0.0-rc.1", "1.0.0-snapshot", 1}, // snapshot is a dev phase, before pre-releases
+		{"1.0.0-x.7", "1.0.0", -1},
+		{"1.2.3+build", "1.2.3", 1},
+		{"v22.1.0", "22.1.0", 1}, // equal versions, raw string tie-breaker
+		{"3.13.0", "3.13.0t", -1},
+		{"2024.10", "2024.2", 1},
+		{"system", "0.1", -1},
+		{"miniconda3-4.7.12", "anaconda3-2024.02-1", 1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestCompareTransitive(t *testing.T) {
+	// synonyms, known phases and unrecognized labels, which must sort consistently
+	versions := []string{
+		"1.0a1", "1.0ab1", "1.0alpha1", "1.0b1", "1.0beta1", "1.0c1", "1.0d1", "1.0rc1",
+		"1.0pre1", "1.0preview1", "1.0-gamma", "1.0-x.7", "1.0-snapshot", "1.0.dev1", "1.0", "1.0.post1",
+	}
+	var parsed []Version
+	for _, s := range versions {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, v)
+	}
+	// without the raw string tie-breaker, synonyms are equal
+	for _, a := range parsed {
+		for _, b := range parsed {
+			for _, c := range parsed {
+				ab, bc, ac := a.Compare(b), b.Compare(c), a.Compare(c)
+				if ab <= 0 && bc <= 0 && (ac > 0 || ac == 0 && (ab < 0 || bc < 0)) {
+					t.Errorf("%s <= %s <= %s, but %s.Compare(%s) = %d", a.Raw, b.Raw, c.Raw, a.Raw, c.Raw, ac)
+				}
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		stable  bool
+		wantErr bool
+	}{
+		{in: "3.12.8", stable: true},
+		{in: "2024.1", stable: true},
+		{in: "v20.11.1", stable: true},
+		{in: "1.0.post1", stable: true},
+		{in: "1.2.3+build.5", stable: true},
+		{in: "3.13.0rc1", stable: false},
+		{in: "1.2.0-beta", stable: false},
+		{in: "3.12-dev", stable: false},
+		{in: "3.13.0t", stable: false},
+		{in: "system", wantErr: true},
+		{in: "pypy3.10-7.3.17", wantErr: true},
+	}
+	for _, tt := range tests {
+		v, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && v.IsStable() != tt.stable {
+			t.Errorf("Parse(%q).IsStable() = %v, want %v", tt.in, v.IsStable(), tt.stable)
+		}
+	}
+}